@@ -0,0 +1,117 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonetica
+
+import (
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Backend is an inference engine that loads Bayesnets, such as Netica.
+type Backend interface {
+	// Load parses buf holding the contents of a file with name into a new BackendNetwork.
+	// The extension of name determines the file format.
+	Load(name string, buf []byte, options LoadOptions) (BackendNetwork, error)
+}
+
+// BackendNetwork is a Bayesnet loaded by a Backend, with methods as for Network.
+type BackendNetwork interface {
+	Name() string
+	Title() string
+	Comment() string
+	NodeList() ([]BackendNode, error)
+	NodeNamed(name string) (BackendNode, error)
+	EnterCase(caseMap map[string]string) error
+	ClearCases() error
+	FindingsProbability() (float64, error)
+	CloseNetwork() error
+
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// BackendNode is a node of a BackendNetwork, with methods as for Node.
+type BackendNode interface {
+	Name() string
+	Title() string
+	Comment() string
+	Kind() NodeKind
+	IsDiscreteType() bool
+	StateNameList() ([]string, error)
+	LevelList() ([]float64, error)
+	ParentList() ([]BackendNode, error)
+	ChildList() ([]BackendNode, error)
+	ParseState(state string) (int, error)
+	BeliefList() ([]float64, error)
+	CPT() ([][]float64, error)
+	Value() (float64, float64, error)
+	Infer() (string, error)
+}
+
+// LoadOptions configure how a Network is prepared for inference after it is parsed.
+type LoadOptions struct {
+	// TimeSlices expands a dynamic Bayesnet into this many time slices, 0 to leave it unexpanded.
+	TimeSlices int
+	// Levels discretises each continuous node named in the map with levels as interval boundaries.
+	Levels map[string][]float64
+	// Bins discretises other continuous nodes without levels into this many equal intervals from Low to High, 0 to leave them.
+	// High must be above Low if Bins is set.
+	Bins      int
+	Low, High float64
+	// EquationSamples converts the equation of each node into its table with this many samples, 0 to keep existing tables.
+	EquationSamples int
+}
+
+// LoadFile parses file at path into a new BackendNetwork with backend.
+func LoadFile(backend Backend, path string, options LoadOptions) (BackendNetwork, error) {
+	// Read file and check for errors
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Load(filepath.Base(path), buf, options)
+}
+
+// NodeKind is the kind of a Node in a Bayesnet.
+type NodeKind int
+
+// Node kinds supported by Netica, numbered as in the Netica C API.
+const (
+	NatureNode NodeKind = iota + 1
+	ConstantNode
+	DecisionNode
+	UtilityNode
+	DisconnectedNode
+)
+
+// String returns the name of the NodeKind.
+func (kind NodeKind) String() string {
+	switch kind {
+	case NatureNode:
+		return "nature"
+	case ConstantNode:
+		return "constant"
+	case DecisionNode:
+		return "decision"
+	case UtilityNode:
+		return "utility"
+	case DisconnectedNode:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
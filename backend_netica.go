@@ -0,0 +1,108 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+// neticaBackend is the Backend performing inference with Netica.
+type neticaBackend struct {
+	env *Environment
+}
+
+// neticaNetwork is a Network loaded by the Netica Backend.
+type neticaNetwork struct {
+	*Network
+}
+
+// neticaNode is a Node of a Network loaded by the Netica Backend.
+type neticaNode struct {
+	*Node
+}
+
+// NewNeticaBackend returns a Backend loading Bayesnets into environment.
+func NewNeticaBackend(environment *Environment) Backend {
+	return &neticaBackend{environment}
+}
+
+// Load parses buf holding the contents of a .dne or .neta file with name into a new Network.
+func (backend *neticaBackend) Load(name string, buf []byte, options LoadOptions) (BackendNetwork, error) {
+	net, err := NewNetworkFromBytes(backend.env, name, buf, options)
+	if err != nil {
+		return nil, err
+	}
+	return &neticaNetwork{net}, nil
+}
+
+// NeticaNetwork returns the Network underlying net if it was loaded by the Netica Backend.
+func NeticaNetwork(net BackendNetwork) (*Network, bool) {
+	if net, ok := net.(*neticaNetwork); ok {
+		return net.Network, true
+	}
+	return nil, false
+}
+
+// NeticaNode returns the Node underlying node if it was loaded by the Netica Backend.
+func NeticaNode(node BackendNode) (*Node, bool) {
+	if node, ok := node.(*neticaNode); ok {
+		return node.Node, true
+	}
+	return nil, false
+}
+
+// NodeList returns a Slice of Nodes sorted by name lexicographically ascending.
+func (net *neticaNetwork) NodeList() ([]BackendNode, error) {
+	nodes, err := net.Network.NodeList()
+	if err != nil {
+		return nil, err
+	}
+	return backendNodes(nodes), nil
+}
+
+// NodeNamed returns Node in net with name.
+func (net *neticaNetwork) NodeNamed(name string) (BackendNode, error) {
+	node, err := net.Network.NodeNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	return &neticaNode{node}, nil
+}
+
+// ParentList returns a Slice of parent Nodes in the order of the conditional probability table.
+func (node *neticaNode) ParentList() ([]BackendNode, error) {
+	nodes, err := node.Node.ParentList()
+	if err != nil {
+		return nil, err
+	}
+	return backendNodes(nodes), nil
+}
+
+// ChildList returns a Slice of child Nodes.
+func (node *neticaNode) ChildList() ([]BackendNode, error) {
+	nodes, err := node.Node.ChildList()
+	if err != nil {
+		return nil, err
+	}
+	return backendNodes(nodes), nil
+}
+
+// backendNodes wraps nodes as BackendNodes in order.
+func backendNodes(nodes []*Node) []BackendNode {
+	var wrapped []BackendNode
+	for _, node := range nodes {
+		wrapped = append(wrapped, &neticaNode{node})
+	}
+	return wrapped
+}
@@ -0,0 +1,138 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+/*
+#cgo darwin CFLAGS: -I"${SRCDIR}/cgo/lib/darwin"
+#cgo darwin,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/darwin/amd64"
+#cgo darwin LDFLAGS: -lm -lnetica -lpthread -lstdc++
+#cgo linux CFLAGS: -I"${SRCDIR}/cgo/lib/linux"
+#cgo linux,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/386"
+#cgo linux,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/amd64"
+#cgo linux LDFLAGS: -lm -lrt -lnetica -lpthread -lstdc++
+#cgo windows CFLAGS: -I"${SRCDIR}/cgo/lib/windows"
+#cgo windows,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/386"
+#cgo windows,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/amd64"
+#cgo windows LDFLAGS: -lm -llibNetica -lpthread -lstdc++
+#include "stdlib.h"
+#include "Netica.h"
+*/
+import "C"
+
+import (
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// CaseReader reads cases from a Netica case file into the findings of a Network one at a time.
+type CaseReader struct {
+	net    *Network
+	cStrm  *C.stream_ns
+	cNodes *C.nodelist_bn
+	cPosn  C.caseposn_bn
+}
+
+// NewCaseReader opens the Netica case file at path for reading findings of nodes into net.
+// Columns of the case file for other nodes are ignored.
+func NewCaseReader(net *Network, path string, nodes []*Node) (*CaseReader, error) {
+	var reader = &CaseReader{net: net, cPosn: C.FIRST_CASE}
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(nodes)
+	if err != nil {
+		return nil, err
+	}
+	reader.cNodes = cNodes
+	// Allocate path string
+	cPath := (*C.char)(C.CString(path))
+	defer C.free(unsafe.Pointer(cPath))
+	// Allocate file stream and check for errors
+	reader.cStrm = C.NewFileStream_ns(cPath, net.env.c, nil)
+	if err := net.Errors(); err != nil {
+		C.DeleteNodeList_bn(cNodes)
+		return nil, err
+	}
+	return reader, nil
+}
+
+// CloseCaseReader closes the CaseReader, freeing resources.
+func (reader *CaseReader) CloseCaseReader() error {
+	C.DeleteStream_ns(reader.cStrm)
+	C.DeleteNodeList_bn(reader.cNodes)
+	return reader.net.Errors()
+}
+
+// Read replaces the findings of the nodes of the CaseReader with the next case in the file,
+// returning its IDnum and NumCases columns, or -1 if absent. Returns io.EOF after the last case.
+// A case that cannot be read returns an error and is skipped by the following Read.
+func (reader *CaseReader) Read() (int64, float64, error) {
+	var cID C.long
+	var cFreq C.double
+	// Check for end of file
+	if reader.cPosn == C.NO_MORE_CASES {
+		return 0, 0, io.EOF
+	}
+	// Read case into findings and check for errors, advancing past it on error
+	C.ReadNetFindings2_bn(&reader.cPosn, reader.cStrm, C.FALSE, reader.cNodes, &cID, &cFreq)
+	if err := reader.net.Errors(); err != nil {
+		if reader.cPosn != C.NO_MORE_CASES {
+			reader.cPosn = C.NEXT_CASE
+		}
+		reader.net.emit(FindingsEvent)
+		return 0, 0, err
+	}
+	if reader.cPosn == C.NO_MORE_CASES {
+		return 0, 0, io.EOF
+	}
+	reader.net.emit(FindingsEvent)
+	// Advance to next case on following read
+	reader.cPosn = C.NEXT_CASE
+	return int64(cID), float64(cFreq), nil
+}
+
+// FormatCase returns the findings entered at nodes formatted by Netica as the header and row of a case file,
+// without line endings. IDnum and NumCases columns are included if id and freq are not negative.
+func (net *Network) FormatCase(nodes []*Node, id int64, freq float64) (string, string, error) {
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(nodes)
+	if err != nil {
+		return "", "", err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Allocate name string
+	cName := (*C.char)(C.CString("case.cas"))
+	defer C.free(unsafe.Pointer(cName))
+	// Allocate memory stream and check for errors
+	cStrm := C.NewMemoryStream_ns(cName, net.env.c, nil)
+	defer C.DeleteStream_ns(cStrm)
+	if err := net.Errors(); err != nil {
+		return "", "", err
+	}
+	// Write findings into stream and check for errors
+	C.WriteNetFindings_bn(cNodes, cStrm, C.long(id), C.double(freq))
+	if err := net.Errors(); err != nil {
+		return "", "", err
+	}
+	// Split stream contents into header and row
+	var cLen C.long
+	cBuf := C.GetStreamContents_ns(cStrm, &cLen)
+	lines := strings.Split(strings.TrimRight(C.GoStringN(cBuf, C.int(cLen)), "\r\n"), "\n")
+	header := strings.TrimRight(lines[0], "\r")
+	row := strings.TrimRight(lines[len(lines)-1], "\r")
+	return header, row, nil
+}
@@ -0,0 +1,165 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+/*
+#cgo darwin CFLAGS: -I"${SRCDIR}/cgo/lib/darwin"
+#cgo darwin,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/darwin/amd64"
+#cgo darwin LDFLAGS: -lm -lnetica -lpthread -lstdc++
+#cgo linux CFLAGS: -I"${SRCDIR}/cgo/lib/linux"
+#cgo linux,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/386"
+#cgo linux,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/amd64"
+#cgo linux LDFLAGS: -lm -lrt -lnetica -lpthread -lstdc++
+#cgo windows CFLAGS: -I"${SRCDIR}/cgo/lib/windows"
+#cgo windows,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/386"
+#cgo windows,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/amd64"
+#cgo windows LDFLAGS: -lm -llibNetica -lpthread -lstdc++
+#include "stdlib.h"
+#include "Netica.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// Caseset is a set of cases for learning and testing Bayesnets.
+type Caseset struct {
+	c *C.caseset_cs
+
+	env    *Environment
+	cStrms []*C.stream_ns
+}
+
+// NewCaseset returns a new empty Caseset with name.
+func NewCaseset(environment *Environment, name string) (*Caseset, error) {
+	var cases = new(Caseset)
+	cases.env = environment
+	// Allocate name string
+	cName := (*C.char)(C.CString(name))
+	defer C.free(unsafe.Pointer(cName))
+	// Create Netica caseset and check for errors
+	cases.c = C.NewCaseset_cs(cName, cases.env.c)
+	if err := cases.Errors(); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// CloseCaseset closes the Caseset, freeing resources.
+func (cases *Caseset) CloseCaseset() error {
+	// Delete caseset and the streams it reads from
+	C.DeleteCaseset_cs(cases.c)
+	for _, cStrm := range cases.cStrms {
+		C.DeleteStream_ns(cStrm)
+	}
+	cases.cStrms = nil
+	return cases.Errors()
+}
+
+// Errors returns all Netica errors of severity level error since it was last called.
+func (cases *Caseset) Errors() error {
+	return cases.env.Errors()
+}
+
+// AddFile adds the cases in the Netica case file at path, a comma or tab delimited file
+// with a header row of node names, each case counted degree times.
+func (cases *Caseset) AddFile(path string, degree float64) error {
+	// Allocate path string
+	cPath := (*C.char)(C.CString(path))
+	defer C.free(unsafe.Pointer(cPath))
+	// Allocate file stream and check for errors
+	cStrm := C.NewFileStream_ns(cPath, cases.env.c, nil)
+	if err := cases.Errors(); err != nil {
+		return err
+	}
+	return cases.addStream(cStrm, degree)
+}
+
+// AddCases adds cases mapping node names to state names or real values, each case counted degree times.
+// Nodes missing from a case are treated as unobserved.
+func (cases *Caseset) AddCases(caseMaps []map[string]string, degree float64) error {
+	// Write cases as tab delimited case file
+	buf := caseFile(caseMaps)
+	// Allocate name string
+	cName := (*C.char)(C.CString("cases.cas"))
+	defer C.free(unsafe.Pointer(cName))
+	// Allocate memory stream and check for errors
+	cStrm := C.NewMemoryStream_ns(cName, cases.env.c, nil)
+	if err := cases.Errors(); err != nil {
+		return err
+	}
+	// Copy case file into stream and check for errors
+	cBuf := (*C.char)(C.CBytes(buf))
+	defer C.free(unsafe.Pointer(cBuf))
+	C.SetStreamContents_ns(cStrm, cBuf, C.long(len(buf)), C.TRUE)
+	if err := cases.Errors(); err != nil {
+		C.DeleteStream_ns(cStrm)
+		return err
+	}
+	return cases.addStream(cStrm, degree)
+}
+
+// addStream adds the cases in cStrm, which is kept open until the Caseset is closed.
+func (cases *Caseset) addStream(cStrm *C.stream_ns, degree float64) error {
+	C.AddFileToCaseset_cs(cases.c, cStrm, C.double(degree), nil)
+	// Check for errors, delete stream on error
+	if err := cases.Errors(); err != nil {
+		C.DeleteStream_ns(cStrm)
+		return err
+	}
+	cases.cStrms = append(cases.cStrms, cStrm)
+	return nil
+}
+
+// caseFile returns caseMaps written as a tab delimited Netica case file.
+func caseFile(caseMaps []map[string]string) []byte {
+	var buf bytes.Buffer
+	var names []string
+	// Collect names of all nodes in any case
+	seen := make(map[string]bool)
+	for _, caseMap := range caseMaps {
+		for name := range caseMap {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	// Write header row of node names
+	buf.WriteString(strings.Join(names, "\t"))
+	buf.WriteString("\n")
+	// Write a row per case, with * for missing values
+	for _, caseMap := range caseMaps {
+		values := make([]string, len(names))
+		for index, name := range names {
+			if value, ok := caseMap[name]; ok && value != "" {
+				values[index] = value
+			} else {
+				values[index] = "*"
+			}
+		}
+		buf.WriteString(strings.Join(values, "\t"))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,378 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica/dne"
+)
+
+// bifEntry is an entry of a BIF probability block, a row for parent states, a default row or a whole table.
+type bifEntry struct {
+	states []string
+	probs  []float64
+	table  bool
+	line   int
+}
+
+// bifProbability is a BIF probability block of a variable given its parents.
+type bifProbability struct {
+	node    *dne.Node
+	parents []string
+	entries []bifEntry
+}
+
+// readBIF reads a BIF file, the Bayesian Interchange Format of JavaBayes, bnlearn and pgmpy, from r.
+func readBIF(r io.Reader) (*dne.Net, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	net, err := parseBIF(newScanner(src, "{}()[]|,;", "//"))
+	if err != nil {
+		return nil, fmt.Errorf("In function convert.readBIF: %v", err)
+	}
+	return net, nil
+}
+
+// parseBIF parses the network, variable and probability blocks of a BIF file.
+func parseBIF(s *scanner) (*dne.Net, error) {
+	var blocks []bifProbability
+	net := &dne.Net{}
+	for !s.eof {
+		switch {
+		case s.is("network"):
+			s.next()
+			if !s.is("{") {
+				name, err := s.word()
+				if err != nil {
+					return nil, err
+				}
+				if name != "unknown" {
+					net.Name = name
+				}
+			}
+			if err := skipBIFBlock(s); err != nil {
+				return nil, err
+			}
+		case s.is("variable"):
+			s.next()
+			node, err := parseBIFVariable(s)
+			if err != nil {
+				return nil, err
+			}
+			net.Nodes = append(net.Nodes, node)
+		case s.is("probability"):
+			s.next()
+			block, err := parseBIFProbability(s, net)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
+		default:
+			return nil, s.errorf("unexpected %q", s.tok)
+		}
+	}
+	// Convert entries now the states of every variable are known
+	var probs = make(map[*dne.Node][]float64)
+	for _, block := range blocks {
+		block.node.Parents = block.parents
+		table, err := bifTable(net, block)
+		if err != nil {
+			return nil, err
+		}
+		probs[block.node] = table
+	}
+	return net, setProbs(net, probs)
+}
+
+// skipBIFBlock skips a braced block of properties.
+func skipBIFBlock(s *scanner) error {
+	if err := s.expect("{"); err != nil {
+		return err
+	}
+	for !s.is("}") {
+		if err := s.skipTo(";"); err != nil {
+			return err
+		}
+	}
+	s.next()
+	return nil
+}
+
+// parseBIFVariable parses name { type discrete [ n ] { states }; properties } into a Node.
+func parseBIFVariable(s *scanner) (*dne.Node, error) {
+	name, err := s.word()
+	if err != nil {
+		return nil, err
+	}
+	node := newNode(name, nil)
+	if err := s.expect("{"); err != nil {
+		return nil, err
+	}
+	for !s.is("}") {
+		if !s.is("type") {
+			// Read position property, skipping others
+			if s.is("property") {
+				s.next()
+				var x, y float64
+				if _, err := fmt.Sscanf(s.tok, "position = (%g, %g)", &x, &y); s.quoted && err == nil {
+					node.Visual = &dne.Visual{X: x, Y: y}
+				} else if s.is("position") {
+					var values []float64
+					for s.next(); !s.is(";") && !s.eof; s.next() {
+						if value, err := strconv.ParseFloat(s.tok, 64); err == nil {
+							values = append(values, value)
+						}
+					}
+					if len(values) == 2 {
+						node.Visual = &dne.Visual{X: values[0], Y: values[1]}
+					}
+				}
+			}
+			if err := s.skipTo(";"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		s.next()
+		if !s.is("discrete") {
+			return nil, s.errorf("variable %s is of type %s, only discrete variables are supported", name, s.tok)
+		}
+		s.next()
+		if err := s.skipTo("]"); err != nil {
+			return nil, err
+		}
+		if err := s.expect("{"); err != nil {
+			return nil, err
+		}
+		for !s.is("}") {
+			state, err := s.word()
+			if err != nil {
+				return nil, err
+			}
+			node.States = append(node.States, state)
+			if s.is(",") {
+				s.next()
+			}
+		}
+		s.next()
+		if err := s.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	s.next()
+	return node, nil
+}
+
+// parseBIFProbability parses ( node | parents ) { entries } for a variable of net.
+func parseBIFProbability(s *scanner, net *dne.Net) (bifProbability, error) {
+	var block bifProbability
+	if err := s.expect("("); err != nil {
+		return block, err
+	}
+	name, err := s.word()
+	if err != nil {
+		return block, err
+	}
+	if block.node = net.NodeNamed(name); block.node == nil {
+		return block, s.errorf("probability of undefined variable %s", name)
+	}
+	// Read comma separated parents, or space separated after the node in older files
+	if s.is("|") {
+		s.next()
+	}
+	if !s.is(")") {
+		for !s.is(")") {
+			parent, err := s.word()
+			if err != nil {
+				return block, err
+			}
+			block.parents = append(block.parents, parent)
+			if s.is(",") {
+				s.next()
+			}
+		}
+	}
+	if err := s.expect(")"); err != nil {
+		return block, err
+	}
+	if err := s.expect("{"); err != nil {
+		return block, err
+	}
+	for !s.is("}") {
+		entry := bifEntry{line: s.line}
+		switch {
+		case s.is("table"):
+			entry.table = true
+			s.next()
+		case s.is("default"):
+			s.next()
+		case s.is("("):
+			// Read parent states of row
+			for s.next(); !s.is(")"); {
+				state, err := s.word()
+				if err != nil {
+					return block, err
+				}
+				entry.states = append(entry.states, state)
+				if s.is(",") {
+					s.next()
+				}
+			}
+			s.next()
+		default:
+			if err := s.skipTo(";"); err != nil {
+				return block, err
+			}
+			continue
+		}
+		// Read comma or space separated probabilities up to semicolon
+		for !s.is(";") {
+			if s.is(",") {
+				s.next()
+				continue
+			}
+			prob, err := strconv.ParseFloat(s.tok, 64)
+			if err != nil || s.eof {
+				return block, s.errorf("expected probability but found %q", s.tok)
+			}
+			entry.probs = append(entry.probs, prob)
+			s.next()
+		}
+		s.next()
+		block.entries = append(block.entries, entry)
+	}
+	s.next()
+	return block, nil
+}
+
+// bifTable returns the probabilities of a probability block in file order.
+// A table lists them with the node state varying slowest, rows by parent states override a default row.
+func bifTable(net *dne.Net, block bifProbability) ([]float64, error) {
+	numStates, err := parentStates(net, block.node)
+	if err != nil {
+		return nil, err
+	}
+	rows, size := product(numStates), block.node.NumStates()
+	var probs = make([]float64, rows*size)
+	var set = make([]bool, rows)
+	for _, entry := range block.entries {
+		if entry.table || entry.states == nil {
+			if entry.table && len(entry.probs) != rows*size || !entry.table && len(entry.probs) != size {
+				return nil, fmt.Errorf("line %d: variable %s has %d probabilities", entry.line, block.node.Name, len(entry.probs))
+			}
+			for row := 0; row < rows; row++ {
+				if set[row] && !entry.table {
+					continue
+				}
+				for state := 0; state < size; state++ {
+					if entry.table {
+						probs[row*size+state] = entry.probs[state*rows+row]
+					} else {
+						probs[row*size+state] = entry.probs[state]
+					}
+				}
+				set[row] = entry.table
+			}
+			continue
+		}
+		// Find row of parent states, the last parent varying fastest
+		if len(entry.states) != len(block.parents) || len(entry.probs) != size {
+			return nil, fmt.Errorf("line %d: variable %s has %d parent states and %d probabilities", entry.line, block.node.Name, len(entry.states), len(entry.probs))
+		}
+		row := 0
+		for index, state := range entry.states {
+			parent := net.NodeNamed(block.parents[index])
+			position := indexOf(parent.States, state)
+			if position < 0 {
+				return nil, fmt.Errorf("line %d: variable %s has no state %s", entry.line, parent.Name, state)
+			}
+			row = row*numStates[index] + position
+		}
+		copy(probs[row*size:(row+1)*size], entry.probs)
+		set[row] = true
+	}
+	return probs, nil
+}
+
+// writeBIF writes net to w as a BIF file with a row of probabilities per combination of parent states.
+func writeBIF(w io.Writer, net *dne.Net) error {
+	if err := checkNet(net); err != nil {
+		return fmt.Errorf("In function convert.writeBIF: %v", err)
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "network %s {\n}\n", net.Name)
+	for _, node := range net.Nodes {
+		states := stateNames(node)
+		fmt.Fprintf(out, "variable %s {\n  type discrete [ %d ] { %s };\n", node.Name, len(states), strings.Join(states, ", "))
+		if node.Visual != nil {
+			fmt.Fprintf(out, "  property position = (%s, %s) ;\n", formatFloat(node.Visual.X), formatFloat(node.Visual.Y))
+		}
+		fmt.Fprintf(out, "}\n")
+	}
+	for _, node := range net.Nodes {
+		if len(node.Parents) == 0 {
+			fmt.Fprintf(out, "probability ( %s ) {\n  table %s;\n}\n", node.Name, joinFloats(node.Probs[0], ", "))
+			continue
+		}
+		fmt.Fprintf(out, "probability ( %s | %s ) {\n", node.Name, strings.Join(node.Parents, ", "))
+		var parents []*dne.Node
+		for _, name := range node.Parents {
+			parents = append(parents, net.NodeNamed(name))
+		}
+		for row, probs := range node.Probs {
+			fmt.Fprintf(out, "  (%s) %s;\n", strings.Join(rowStates(parents, row), ", "), joinFloats(probs, ", "))
+		}
+		fmt.Fprintf(out, "}\n")
+	}
+	return out.Flush()
+}
+
+// rowStates returns the state names of parents in row, the last parent varying fastest.
+func rowStates(parents []*dne.Node, row int) []string {
+	var states = make([]string, len(parents))
+	for index := len(parents) - 1; index >= 0; index-- {
+		names := stateNames(parents[index])
+		states[index] = names[row%len(names)]
+		row /= len(names)
+	}
+	return states
+}
+
+// joinFloats formats numbers separated by sep.
+func joinFloats(numbers []float64, sep string) string {
+	var texts []string
+	for _, number := range numbers {
+		texts = append(texts, formatFloat(number))
+	}
+	return strings.Join(texts, sep)
+}
+
+// indexOf returns the index of text in texts, or -1 if absent.
+func indexOf(texts []string, text string) int {
+	for index, other := range texts {
+		if other == text {
+			return index
+		}
+	}
+	return -1
+}
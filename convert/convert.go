@@ -0,0 +1,380 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert reads and writes Bayesnets in the file formats of other tools.
+//
+// Each Format converts between its files and the typed model of package dne,
+// so Bayesnets from XMLBIF, BIF, Hugin .net, GeNIe .xdsl and UAI files can be
+// saved as .dne for Netica, and the other way round. Backend wraps another
+// Backend to load Bayesnets in any supported format.
+package convert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica"
+	"github.com/slee21/gonetica/dne"
+)
+
+// Format is a Bayesnet file format with its file extensions.
+type Format struct {
+	Name  string
+	Exts  []string
+	Read  func(r io.Reader) (*dne.Net, error)
+	Write func(w io.Writer, net *dne.Net) error
+}
+
+// Formats lists the supported file formats.
+var Formats = []*Format{
+	{"dne", []string{".dne"}, dne.Read, writeDNE},
+	{"xmlbif", []string{".xmlbif", ".xml"}, readXMLBIF, writeXMLBIF},
+	{"bif", []string{".bif"}, readBIF, writeBIF},
+	{"hugin", []string{".net"}, readHugin, writeHugin},
+	{"xdsl", []string{".xdsl"}, readXDSL, writeXDSL},
+	{"uai", []string{".uai"}, readUAI, writeUAI},
+}
+
+// Backend loads Bayesnets in any supported format with another Backend, converting them to .dne first.
+type Backend struct {
+	gonetica.Backend
+}
+
+// NewBackend returns a new Backend converting Bayesnets for backend.
+func NewBackend(backend gonetica.Backend) *Backend {
+	return &Backend{backend}
+}
+
+// Load converts buf holding the contents of a file with name to .dne unless already .dne or .neta, then loads it.
+func (backend *Backend) Load(name string, buf []byte, options gonetica.LoadOptions) (gonetica.BackendNetwork, error) {
+	if format := ForPath(name); format != nil && format.Name != "dne" {
+		// Convert file and check for errors
+		net, err := Read(name, buf)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := writeDNE(&out, net); err != nil {
+			return nil, err
+		}
+		name, buf = strings.TrimSuffix(name, filepath.Ext(name))+".dne", out.Bytes()
+	}
+	return backend.Backend.Load(name, buf, options)
+}
+
+// ForPath returns the Format of the file at path by its extension, or nil if not supported.
+func ForPath(path string) *Format {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, format := range Formats {
+		for _, formatExt := range format.Exts {
+			if ext == formatExt {
+				return format
+			}
+		}
+	}
+	return nil
+}
+
+// Named returns the Format with name, or nil if not supported.
+func Named(name string) *Format {
+	for _, format := range Formats {
+		if format.Name == name {
+			return format
+		}
+	}
+	return nil
+}
+
+// Read reads buf holding the contents of a file with name in the format of its extension.
+// Bayesnets from other formats are named after the file if unnamed, and their names made valid for Netica.
+func Read(name string, buf []byte) (*dne.Net, error) {
+	format := ForPath(name)
+	if format == nil {
+		return nil, fmt.Errorf("In function convert.Read: unsupported file format %s", filepath.Ext(name))
+	}
+	// Read file and check for errors
+	net, err := format.Read(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	if format.Name == "dne" {
+		return net, nil
+	}
+	if net.Name == "" {
+		net.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	rename(net)
+	return net, sortNodes(net)
+}
+
+// Write writes net to w in the format of the extension of name.
+func Write(w io.Writer, name string, net *dne.Net) error {
+	format := ForPath(name)
+	if format == nil {
+		return fmt.Errorf("In function convert.Write: unsupported file format %s", filepath.Ext(name))
+	}
+	return format.Write(w, net)
+}
+
+// writeDNE encodes net and writes it to w as a .dne file.
+func writeDNE(w io.Writer, net *dne.Net) error {
+	file, err := dne.Encode(net)
+	if err != nil {
+		return err
+	}
+	return dne.Write(w, file)
+}
+
+// newXMLDecoder returns a decoder of XML from r, reading ASCII and Latin-1 encoded files as well as UTF-8.
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "us-ascii", "ascii", "iso-8859-1", "latin1", "latin-1":
+			buf, err := ioutil.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			// Each Latin-1 byte is the code point of its character
+			var runes = make([]rune, len(buf))
+			for index, c := range buf {
+				runes[index] = rune(c)
+			}
+			return strings.NewReader(string(runes)), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+	return decoder
+}
+
+// newNode returns a discrete nature Node with name and states.
+func newNode(name string, states []string) *dne.Node {
+	return &dne.Node{Name: name, Kind: gonetica.NatureNode, Discrete: true, States: states}
+}
+
+// parentStates returns the number of states of each parent of node in net.
+func parentStates(net *dne.Net, node *dne.Node) ([]int, error) {
+	var numStates []int
+	for _, name := range node.Parents {
+		parent := net.NodeNamed(name)
+		if parent == nil {
+			return nil, fmt.Errorf("parent %s of node %s not defined", name, node.Name)
+		}
+		numStates = append(numStates, parent.NumStates())
+	}
+	return numStates, nil
+}
+
+// setProbs splits the probabilities of each node in file order, the node state varying fastest
+// then the last parent state, into one row per combination of parent states.
+func setProbs(net *dne.Net, probs map[*dne.Node][]float64) error {
+	for _, node := range net.Nodes {
+		numStates, err := parentStates(net, node)
+		if err != nil {
+			return err
+		}
+		rows := product(numStates)
+		size := node.NumStates()
+		if len(probs[node]) != rows*size {
+			return fmt.Errorf("node %s has %d probabilities but %d parent state combinations of %d states", node.Name, len(probs[node]), rows, size)
+		}
+		node.Probs = nil
+		for row := 0; row < rows; row++ {
+			node.Probs = append(node.Probs, probs[node][row*size:(row+1)*size])
+		}
+	}
+	return nil
+}
+
+// checkNet returns an error if a node of net cannot be written to a format other than .dne.
+func checkNet(net *dne.Net) error {
+	for _, node := range net.Nodes {
+		if node.Kind != gonetica.NatureNode {
+			return fmt.Errorf("node %s is a %s node, only nature nodes are supported", node.Name, node.Kind)
+		}
+		if node.NumStates() == 0 {
+			return fmt.Errorf("node %s has no states, continuous nodes must be discretised", node.Name)
+		}
+		numStates, err := parentStates(net, node)
+		if err != nil {
+			return err
+		}
+		if len(node.Probs) != product(numStates) {
+			return fmt.Errorf("node %s has no conditional probability table", node.Name)
+		}
+		for _, row := range node.Probs {
+			for _, prob := range row {
+				if math.IsNaN(prob) {
+					return fmt.Errorf("node %s has undefined probabilities", node.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stateNames returns the state names of node, naming unnamed states by index.
+func stateNames(node *dne.Node) []string {
+	var names []string
+	for index := 0; index < node.NumStates(); index++ {
+		if index < len(node.States) && node.States[index] != "" {
+			names = append(names, node.States[index])
+		} else {
+			names = append(names, "state"+strconv.Itoa(index))
+		}
+	}
+	return names
+}
+
+// product returns the product of numbers, 1 if none.
+func product(numbers []int) int {
+	result := 1
+	for _, number := range numbers {
+		result *= number
+	}
+	return result
+}
+
+// flatten returns the probability rows of node in file order.
+func flatten(node *dne.Node) []float64 {
+	var probs []float64
+	for _, row := range node.Probs {
+		probs = append(probs, row...)
+	}
+	return probs
+}
+
+// formatFloat formats number in its shortest representation.
+func formatFloat(number float64) string {
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+// parseFloats parses whitespace or comma separated numbers.
+func parseFloats(text string) ([]float64, error) {
+	var numbers []float64
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' }) {
+		number, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+// rename makes the names of the nodes and states of net valid Netica identifiers,
+// keeping the original names as titles.
+func rename(net *dne.Net) {
+	var names = make(map[string]string)
+	var used = make(map[string]bool)
+	net.Name = identifier(net.Name, "Net", nil)
+	for _, node := range net.Nodes {
+		name := identifier(node.Name, "Node", used)
+		if name != node.Name && node.Title == "" {
+			node.Title = node.Name
+		}
+		names[node.Name], node.Name = name, name
+		// Rename states, keeping original state names as state titles if changed
+		var usedStates = make(map[string]bool)
+		var titles []string
+		changed := false
+		for index, state := range node.States {
+			if state == "" {
+				titles = append(titles, "")
+				continue
+			}
+			node.States[index] = identifier(state, "s", usedStates)
+			titles = append(titles, state)
+			changed = changed || node.States[index] != state
+		}
+		if changed && len(node.StateTitles) == 0 {
+			node.StateTitles = titles
+		}
+	}
+	for _, node := range net.Nodes {
+		for index, parent := range node.Parents {
+			if name, ok := names[parent]; ok {
+				node.Parents[index] = name
+			}
+		}
+	}
+}
+
+// identifier returns name as a valid Netica identifier of at most 30 letters, digits or underscores
+// starting with a letter, prefixed if needed and unique among used if not nil.
+func identifier(name string, prefix string, used map[string]bool) string {
+	var id []byte
+	for _, c := range []byte(name) {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' {
+			id = append(id, c)
+		} else {
+			id = append(id, '_')
+		}
+	}
+	if len(id) == 0 || !(id[0] >= 'a' && id[0] <= 'z' || id[0] >= 'A' && id[0] <= 'Z') {
+		id = append([]byte(prefix), id...)
+	}
+	if len(id) > 30 {
+		id = id[:30]
+	}
+	unique := string(id)
+	// Add suffix until unique
+	for suffix := 2; used != nil && used[unique]; suffix++ {
+		tail := "_" + strconv.Itoa(suffix)
+		base := string(id)
+		if len(base)+len(tail) > 30 {
+			base = base[:30-len(tail)]
+		}
+		unique = base + tail
+	}
+	if used != nil {
+		used[unique] = true
+	}
+	return unique
+}
+
+// sortNodes orders the nodes of net so each follows its parents, keeping file order otherwise.
+func sortNodes(net *dne.Net) error {
+	var sorted []*dne.Node
+	var placed = make(map[string]bool)
+	for len(sorted) < len(net.Nodes) {
+		progress := false
+		for _, node := range net.Nodes {
+			if placed[node.Name] {
+				continue
+			}
+			ready := true
+			for _, parent := range node.Parents {
+				ready = ready && placed[parent]
+			}
+			if ready {
+				sorted = append(sorted, node)
+				placed[node.Name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			return fmt.Errorf("In function convert.Read: network %s has a directed cycle", net.Name)
+		}
+	}
+	net.Nodes = sorted
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slee21/gonetica/dne"
+)
+
+// lawnNode is a node of the lawn fixtures as read.
+type lawnNode struct {
+	name    string
+	states  []string
+	parents []string
+	probs   [][]float64
+}
+
+// lawnNodes returns the nodes of the lawn fixtures, with the table of Wet given and names
+// as in named formats, or numbered as in UAI files.
+func lawnNodes(wet [][]float64, numbered bool) []lawnNode {
+	nodes := []lawnNode{
+		{"Rain", []string{"yes", "no"}, nil, [][]float64{{0.2, 0.8}}},
+		{"Sprinkler", []string{"on", "off"}, nil, [][]float64{{0.4, 0.6}}},
+		{"Wet", []string{"yes", "no"}, []string{"Rain", "Sprinkler"}, wet},
+	}
+	if numbered {
+		nodes[0].name, nodes[1].name, nodes[2].name = "X0", "X1", "X2"
+		nodes[2].parents = []string{"X0", "X1"}
+		for index := range nodes {
+			nodes[index].states = []string{"s0", "s1"}
+		}
+	}
+	return nodes
+}
+
+// checkLawn reports the nodes of net that differ from want.
+func checkLawn(t *testing.T, context string, net *dne.Net, want []lawnNode) {
+	if len(net.Nodes) != len(want) {
+		t.Errorf("%s: %d nodes, want %d", context, len(net.Nodes), len(want))
+		return
+	}
+	for index, node := range net.Nodes {
+		expected := want[index]
+		if node.Name != expected.name || !reflect.DeepEqual(node.States, expected.states) ||
+			!equalNames(node.Parents, expected.parents) || !reflect.DeepEqual(node.Probs, expected.probs) {
+			t.Errorf("%s: node %s states %v parents %v probs %v, want %s %v %v %v", context,
+				node.Name, node.States, node.Parents, node.Probs, expected.name, expected.states, expected.parents, expected.probs)
+		}
+	}
+}
+
+// equalNames returns whether a and b hold the same names, treating nil and empty alike.
+func equalNames(a []string, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func TestRead(t *testing.T) {
+	probs := [][]float64{{0.99, 0.01}, {0.8, 0.2}, {0.9, 0.1}, {0, 1}}
+	certain := [][]float64{{1, 0}, {1, 0}, {1, 0}, {0, 1}}
+	tests := []struct {
+		file  string
+		nodes []lawnNode
+		title string
+	}{
+		{"lawn.xmlbif", lawnNodes(probs, false), ""},
+		{"lawn.bif", lawnNodes(probs, false), ""},
+		{"lawn.net", lawnNodes(probs, false), "Wet Grass"},
+		// Deterministic nodes are read as certain tables
+		{"lawn.xdsl", lawnNodes(certain, false), "Wet Grass"},
+		{"lawn.uai", lawnNodes(probs, true), ""},
+	}
+	for _, test := range tests {
+		buf, err := ioutil.ReadFile(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		net, err := Read(test.file, buf)
+		if err != nil {
+			t.Errorf("%s: %s", test.file, err)
+			continue
+		}
+		checkLawn(t, test.file, net, test.nodes)
+		if title := net.Nodes[2].Title; title != test.title {
+			t.Errorf("%s: title %q, want %q", test.file, title, test.title)
+		}
+		// Check positions are read as node centers
+		if visual := net.Nodes[2].Visual; !strings.HasSuffix(test.file, ".uai") && (visual == nil || visual.X != 180 || visual.Y != 140) {
+			t.Errorf("%s: visual %+v, want center (180, 140)", test.file, visual)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	buf, err := ioutil.ReadFile(filepath.Join("testdata", "lawn.bif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := Read("lawn.bif", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probs := [][]float64{{0.99, 0.01}, {0.8, 0.2}, {0.9, 0.1}, {0, 1}}
+	for _, format := range Formats {
+		name := "lawn" + format.Exts[0]
+		var out bytes.Buffer
+		if err := Write(&out, name, net); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		back, err := Read(name, out.Bytes())
+		if err != nil {
+			t.Errorf("%s: read back: %s", name, err)
+			continue
+		}
+		checkLawn(t, name, back, lawnNodes(probs, format.Name == "uai"))
+	}
+}
+
+// TestWriteDNE checks a Netica file with a deterministic node can be written in every format.
+func TestWriteDNE(t *testing.T) {
+	buf, err := ioutil.ReadFile(filepath.Join("..", "dne", "testdata", "asia.dne"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := Read("asia.dne", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range Formats {
+		name := "asia" + format.Exts[0]
+		var out bytes.Buffer
+		if err := Write(&out, name, net); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		back, err := Read(name, out.Bytes())
+		if err != nil {
+			t.Errorf("%s: read back: %s", name, err)
+			continue
+		}
+		if len(back.Nodes) != len(net.Nodes) {
+			t.Errorf("%s: %d nodes, want %d", name, len(back.Nodes), len(net.Nodes))
+			continue
+		}
+		for index, node := range back.Nodes {
+			if !reflect.DeepEqual(node.Probs, net.Nodes[index].Probs) {
+				t.Errorf("%s: node %s probs %v, want %v", name, node.Name, node.Probs, net.Nodes[index].Probs)
+			}
+		}
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	tests := []struct {
+		file string
+		src  string
+	}{
+		{"bad.xmlbif", `<BIF VERSION="0.3"><NETWORK><NAME>Bad</NAME>`},
+		{"bad.xmlbif", `<BIF><NETWORK><VARIABLE TYPE="decision"><NAME>D</NAME></VARIABLE></NETWORK></BIF>`},
+		{"bad.xmlbif", `<BIF><NETWORK><DEFINITION><FOR>Missing</FOR><TABLE>1</TABLE></DEFINITION></NETWORK></BIF>`},
+		{"bad.xmlbif", `<BIF><NETWORK><VARIABLE><NAME>A</NAME><OUTCOME>a</OUTCOME><OUTCOME>b</OUTCOME></VARIABLE><DEFINITION><FOR>A</FOR><TABLE>0.5 x</TABLE></DEFINITION></NETWORK></BIF>`},
+		{"bad.bif", `network Bad {`},
+		{"bad.bif", `variable A { type discrete [ 2 ] { a, b }`},
+		{"bad.bif", `variable A { type continuous; }`},
+		{"bad.bif", `variable A { type discrete [ 2 ] { a, b }; } probability ( A ) { table 0.5, x; }`},
+		{"bad.bif", `variable A { type discrete [ 2 ] { a, b }; } probability ( B ) { table 0.5, 0.5; }`},
+		{"bad.bif", `variable A { type discrete [ 2 ] { a, b }; } probability ( A ) { table 0.5; }`},
+		{"bad.bif", `variable A { type discrete [ 2 ] { a, b }; } variable B { type discrete [ 2 ] { a, b }; } probability ( B | A ) { (c) 0.5, 0.5; }`},
+		{"bad.bif", `unexpected`},
+		{"bad.net", `net {`},
+		{"bad.net", `node A { states = ("a" "b"); } potential ( A ) { }`},
+		{"bad.net", `node A { states = ("a" "b") }`},
+		{"bad.net", `decision D { states = ("a" "b"); }`},
+		{"bad.net", `node A { states = ("a" "b"); } potential ( A ) { data = ( 0.5 x ); }`},
+		{"bad.net", `node A { states = ("a" "b"); } potential ( A | B ) { data = (( 0.5 0.5 )); }`},
+		{"bad.net", `node A { states = ("a" "b"); } }`},
+		{"bad.xdsl", `<smile id="Bad"><nodes><cpt id="A">`},
+		{"bad.xdsl", `<smile id="Bad"><nodes><decision id="D"><state id="a" /></decision></nodes></smile>`},
+		{"bad.xdsl", `<smile id="Bad"><nodes><deterministic id="A"><state id="a" /><resultingstates>b</resultingstates></deterministic></nodes></smile>`},
+		{"bad.xdsl", `<smile id="Bad"><nodes><cpt id="A"><state id="a" /><state id="b" /><probabilities>0.5</probabilities></cpt></nodes></smile>`},
+		{"bad.uai", `MARKOV 1 2 1 1 0 2 0.5 0.5`},
+		{"bad.uai", `BAYES 1 2 1 1 0 2 0.5`},
+		{"bad.uai", `BAYES 1 2 1 1 3 2 0.5 0.5`},
+		{"bad.uai", `BAYES 1 2 1 0 0`},
+		{"bad.uai", `BAYES 1 -2`},
+		{"bad.uai", `BAYES 1 2 1 1 0 2 0.5 x`},
+		{"bad.uai", `BAYES 2 2 2 1 1 0 2 0.5 0.5`},
+	}
+	for _, test := range tests {
+		if _, err := Read(test.file, []byte(test.src)); err == nil {
+			t.Errorf("%s %q: read without error", test.file, test.src)
+		}
+	}
+}
+
+func TestWriteUnsupported(t *testing.T) {
+	net := &dne.Net{Name: "Bad", Nodes: []*dne.Node{newNode("A", []string{"a", "b"})}}
+	for _, format := range Formats {
+		if format.Name == "dne" {
+			continue
+		}
+		if err := Write(ioutil.Discard, "bad"+format.Exts[0], net); err == nil {
+			t.Errorf("%s: node without table written without error", format.Name)
+		}
+	}
+	if err := Write(ioutil.Discard, "bad.txt", net); err == nil {
+		t.Errorf("unsupported extension written without error")
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica/dne"
+)
+
+// huginValue is an attribute value of a Hugin .net file, a word, number or string, or a parenthesised list.
+type huginValue struct {
+	text   string
+	quoted bool
+	list   []huginValue
+}
+
+// readHugin reads a Hugin .net file from r.
+func readHugin(r io.Reader) (*dne.Net, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	net := &dne.Net{}
+	var probs = make(map[*dne.Node][]float64)
+	s := newScanner(src, "{}()=;|", "%")
+	if err := parseHugin(s, net, probs); err != nil {
+		return nil, fmt.Errorf("In function convert.readHugin: %v", err)
+	}
+	if !s.eof {
+		return nil, fmt.Errorf("In function convert.readHugin: %v", s.errorf("unexpected %q", s.tok))
+	}
+	if err := setProbs(net, probs); err != nil {
+		return nil, fmt.Errorf("In function convert.readHugin: %v", err)
+	}
+	return net, nil
+}
+
+// parseHugin parses the net, node and potential blocks of a Hugin .net file up to the end of file or of a class.
+func parseHugin(s *scanner, net *dne.Net, probs map[*dne.Node][]float64) error {
+	for !s.eof && !s.is("}") {
+		switch {
+		case s.is("net"):
+			s.next()
+			attrs, err := parseHuginAttrs(s)
+			if err != nil {
+				return err
+			}
+			if name, ok := attrs["name"]; ok {
+				net.Name = name.text
+			}
+		case s.is("class"):
+			// Read the nodes of an object oriented class as the network
+			s.next()
+			name, err := s.word()
+			if err != nil {
+				return err
+			}
+			net.Name = name
+			if err := s.expect("{"); err != nil {
+				return err
+			}
+			if err := parseHugin(s, net, probs); err != nil {
+				return err
+			}
+			if err := s.expect("}"); err != nil {
+				return err
+			}
+		case s.is("discrete"), s.is("node"):
+			if s.is("discrete") {
+				s.next()
+				if !s.is("node") {
+					return s.errorf("discrete %s nodes are not supported", s.tok)
+				}
+			}
+			s.next()
+			node, err := parseHuginNode(s)
+			if err != nil {
+				return err
+			}
+			net.Nodes = append(net.Nodes, node)
+		case s.is("potential"):
+			s.next()
+			if err := parseHuginPotential(s, net, probs); err != nil {
+				return err
+			}
+		default:
+			// Skip attributes of a class, reject other kinds of node
+			if s.is("continuous") || s.is("decision") || s.is("utility") || s.is("function") || s.is("instance") {
+				return s.errorf("%s nodes are not supported", s.tok)
+			}
+			if err := s.skipTo(";"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseHuginNode parses name { attributes } into a Node.
+func parseHuginNode(s *scanner) (*dne.Node, error) {
+	name, err := s.word()
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := parseHuginAttrs(s)
+	if err != nil {
+		return nil, err
+	}
+	node := newNode(name, nil)
+	node.Title = attrs["label"].text
+	for _, state := range attrs["states"].list {
+		node.States = append(node.States, state.text)
+	}
+	// Read position as center of node
+	if position := attrs["position"].list; len(position) == 2 {
+		x, errX := strconv.ParseFloat(position[0].text, 64)
+		y, errY := strconv.ParseFloat(position[1].text, 64)
+		if errX == nil && errY == nil {
+			node.Visual = &dne.Visual{X: x, Y: y}
+		}
+	}
+	return node, nil
+}
+
+// parseHuginPotential parses ( node | parents ) { data = (...); } into the probabilities of the node of net.
+func parseHuginPotential(s *scanner, net *dne.Net, probs map[*dne.Node][]float64) error {
+	if err := s.expect("("); err != nil {
+		return err
+	}
+	name, err := s.word()
+	if err != nil {
+		return err
+	}
+	node := net.NodeNamed(name)
+	if node == nil {
+		return s.errorf("potential of undefined node %s", name)
+	}
+	// Read space separated parents
+	node.Parents = nil
+	if s.is("|") {
+		s.next()
+		for !s.is(")") {
+			parent, err := s.word()
+			if err != nil {
+				return err
+			}
+			node.Parents = append(node.Parents, parent)
+		}
+	}
+	if err := s.expect(")"); err != nil {
+		return err
+	}
+	attrs, err := parseHuginAttrs(s)
+	if err != nil {
+		return err
+	}
+	data, ok := attrs["data"]
+	if !ok {
+		return s.errorf("potential of node %s has no data", name)
+	}
+	// Flatten nested data, the node state varying fastest
+	var flat func(value huginValue) error
+	flat = func(value huginValue) error {
+		if value.list == nil {
+			prob, err := strconv.ParseFloat(value.text, 64)
+			if err != nil {
+				return fmt.Errorf("potential of node %s: %v", name, err)
+			}
+			probs[node] = append(probs[node], prob)
+		}
+		for _, elem := range value.list {
+			if err := flat(elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return flat(data)
+}
+
+// parseHuginAttrs parses { name = value; ... } into attributes by name.
+func parseHuginAttrs(s *scanner) (map[string]huginValue, error) {
+	var attrs = make(map[string]huginValue)
+	if err := s.expect("{"); err != nil {
+		return nil, err
+	}
+	for !s.is("}") {
+		name, err := s.word()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := parseHuginValue(s)
+		if err != nil {
+			return nil, err
+		}
+		attrs[name] = value
+		if err := s.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	s.next()
+	return attrs, nil
+}
+
+// parseHuginValue parses a word, number, string or parenthesised list of values.
+func parseHuginValue(s *scanner) (huginValue, error) {
+	if !s.is("(") {
+		value := huginValue{text: s.tok, quoted: s.quoted}
+		_, err := s.word()
+		return value, err
+	}
+	value := huginValue{list: []huginValue{}}
+	for s.next(); !s.is(")"); {
+		elem, err := parseHuginValue(s)
+		if err != nil {
+			return value, err
+		}
+		value.list = append(value.list, elem)
+	}
+	s.next()
+	return value, nil
+}
+
+// writeHugin writes net to w as a Hugin .net file.
+func writeHugin(w io.Writer, net *dne.Net) error {
+	if err := checkNet(net); err != nil {
+		return fmt.Errorf("In function convert.writeHugin: %v", err)
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "net\n{\n    node_size = (80 40);\n    name = %s;\n}\n", strconv.Quote(net.Name))
+	for _, node := range net.Nodes {
+		var states []string
+		for _, state := range stateNames(node) {
+			states = append(states, strconv.Quote(state))
+		}
+		fmt.Fprintf(out, "\nnode %s\n{\n    label = %s;\n", node.Name, strconv.Quote(node.Title))
+		if node.Visual != nil {
+			fmt.Fprintf(out, "    position = (%d %d);\n", int(node.Visual.X), int(node.Visual.Y))
+		}
+		fmt.Fprintf(out, "    states = (%s);\n}\n", strings.Join(states, " "))
+	}
+	for _, node := range net.Nodes {
+		if len(node.Parents) == 0 {
+			fmt.Fprintf(out, "\npotential ( %s )\n{\n    data = ( %s );\n}\n", node.Name, joinFloats(node.Probs[0], " "))
+			continue
+		}
+		var parents []*dne.Node
+		for _, name := range node.Parents {
+			parents = append(parents, net.NodeNamed(name))
+		}
+		fmt.Fprintf(out, "\npotential ( %s | %s )\n{\n    data = ", node.Name, strings.Join(node.Parents, " "))
+		// Nest rows in one list per parent, commenting each row with its parent states
+		for row, probs := range node.Probs {
+			states := rowStates(parents, row)
+			opening, closing := 0, 0
+			for index := range parents {
+				size := product(parentSizes(parents[index:]))
+				if row%size == 0 {
+					opening++
+				}
+				if (row+1)%size == 0 {
+					closing++
+				}
+			}
+			indent := ""
+			if row > 0 {
+				indent = "\t   " + strings.Repeat(" ", len(parents)-opening)
+			}
+			var labels []string
+			for index, parent := range parents {
+				labels = append(labels, parent.Name+"="+states[index])
+			}
+			fmt.Fprintf(out, "%s%s( %s )%s", indent, strings.Repeat("(", opening), joinFloats(probs, " "), strings.Repeat(")", closing))
+			if row == len(node.Probs)-1 {
+				fmt.Fprintf(out, ";")
+			}
+			fmt.Fprintf(out, "\t%%  %s\n", strings.Join(labels, "  "))
+		}
+		fmt.Fprintf(out, "}\n")
+	}
+	return out.Flush()
+}
+
+// parentSizes returns the number of states of each of parents.
+func parentSizes(parents []*dne.Node) []int {
+	var sizes []int
+	for _, parent := range parents {
+		sizes = append(sizes, parent.NumStates())
+	}
+	return sizes
+}
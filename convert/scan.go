@@ -0,0 +1,182 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scanner splits the contents of a text Bayesnet file into words, quoted strings and punctuation,
+// skipping whitespace and comments, with the current token in tok.
+type scanner struct {
+	src     []byte
+	pos     int
+	line    int
+	punct   string
+	comment string
+	tok     string
+	quoted  bool
+	eof     bool
+}
+
+// newScanner returns a scanner at the first token of src, with punct as punctuation characters
+// and comment starting line comments. C block comments are always skipped.
+func newScanner(src []byte, punct string, comment string) *scanner {
+	s := &scanner{src: src, line: 1, punct: punct, comment: comment}
+	s.next()
+	return s
+}
+
+// next advances to the next token.
+func (s *scanner) next() {
+	s.skip()
+	s.tok, s.quoted = "", false
+	if s.pos >= len(s.src) {
+		s.eof = true
+		return
+	}
+	start := s.pos
+	switch c := s.src[s.pos]; {
+	case c == '"':
+		// Scan string up to closing quote, skipping escaped characters
+		s.pos++
+		for s.pos < len(s.src) && s.src[s.pos] != '"' {
+			if s.src[s.pos] == '\\' {
+				s.pos++
+			}
+			if s.pos < len(s.src) && s.src[s.pos] == '\n' {
+				s.line++
+			}
+			s.pos++
+		}
+		s.pos++
+		if s.pos > len(s.src) {
+			s.pos = len(s.src)
+		}
+		s.tok, s.quoted = unquote(string(s.src[start:s.pos])), true
+	case strings.IndexByte(s.punct, c) >= 0:
+		s.pos++
+		s.tok = string(c)
+	default:
+		for s.pos < len(s.src) && !isSpace(s.src[s.pos]) && strings.IndexByte(s.punct, s.src[s.pos]) < 0 && s.src[s.pos] != '"' && !s.atComment() {
+			s.pos++
+		}
+		s.tok = string(s.src[start:s.pos])
+	}
+}
+
+// skip advances past whitespace and comments.
+func (s *scanner) skip() {
+	for s.pos < len(s.src) {
+		switch {
+		case s.src[s.pos] == '\n':
+			s.line++
+			s.pos++
+		case isSpace(s.src[s.pos]):
+			s.pos++
+		case s.hasPrefix(s.comment):
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+				s.pos++
+			}
+		case s.hasPrefix("/*"):
+			for s.pos < len(s.src) && !s.hasPrefix("*/") {
+				if s.src[s.pos] == '\n' {
+					s.line++
+				}
+				s.pos++
+			}
+			s.pos += 2
+			if s.pos > len(s.src) {
+				s.pos = len(s.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// atComment returns whether a comment starts at the current position.
+func (s *scanner) atComment() bool {
+	return s.hasPrefix(s.comment) || s.hasPrefix("/*")
+}
+
+// hasPrefix returns whether prefix starts at the current position.
+func (s *scanner) hasPrefix(prefix string) bool {
+	return prefix != "" && strings.HasPrefix(string(s.src[s.pos:min(s.pos+len(prefix), len(s.src))]), prefix)
+}
+
+// is returns whether the current token is the unquoted text.
+func (s *scanner) is(text string) bool {
+	return !s.eof && !s.quoted && s.tok == text
+}
+
+// expect checks the current token is the unquoted text and advances past it.
+func (s *scanner) expect(text string) error {
+	if !s.is(text) {
+		return s.errorf("expected %q but found %q", text, s.tok)
+	}
+	s.next()
+	return nil
+}
+
+// word returns the current token as a name or number and advances past it.
+func (s *scanner) word() (string, error) {
+	if s.eof || !s.quoted && strings.IndexByte(s.punct, s.tok[0]) >= 0 {
+		return "", s.errorf("expected name but found %q", s.tok)
+	}
+	word := s.tok
+	s.next()
+	return word, nil
+}
+
+// skipTo advances past the next unquoted text, such as the semicolon ending a statement.
+func (s *scanner) skipTo(text string) error {
+	for !s.is(text) {
+		if s.eof {
+			return s.errorf("expected %q before end of file", text)
+		}
+		s.next()
+	}
+	s.next()
+	return nil
+}
+
+// errorf returns an error at the line of the current token.
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", s.line, fmt.Sprintf(format, args...))
+}
+
+// isSpace returns whether c is whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// min returns the smaller of a and b.
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// unquote returns text without enclosing quotes and escapes.
+func unquote(text string) string {
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		return unquoted
+	}
+	return strings.Trim(text, "\"")
+}
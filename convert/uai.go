@@ -0,0 +1,156 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica/dne"
+)
+
+// readUAI reads a UAI competition file of a BAYES network from r.
+// Variables are named by index and their states numbered, as the format has no names.
+func readUAI(r io.Reader) (*dne.Net, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(src))
+	net, err := parseUAI(fields)
+	if err != nil {
+		return nil, fmt.Errorf("In function convert.readUAI: %v", err)
+	}
+	return net, nil
+}
+
+// parseUAI parses the preamble and function tables of a UAI file split into fields.
+func parseUAI(fields []string) (*dne.Net, error) {
+	pos := 0
+	// nextInt returns the next field as a non-negative integer
+	nextInt := func() (int, error) {
+		if pos >= len(fields) {
+			return 0, fmt.Errorf("unexpected end of file")
+		}
+		number, err := strconv.Atoi(fields[pos])
+		if err == nil && number < 0 {
+			err = fmt.Errorf("negative number %d", number)
+		}
+		pos++
+		return number, err
+	}
+	if len(fields) == 0 || fields[0] != "BAYES" {
+		return nil, fmt.Errorf("only BAYES networks are supported")
+	}
+	pos++
+	numVars, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	net := &dne.Net{}
+	for index := 0; index < numVars; index++ {
+		card, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		var states []string
+		for state := 0; state < card; state++ {
+			states = append(states, "s"+strconv.Itoa(state))
+		}
+		net.Nodes = append(net.Nodes, newNode("X"+strconv.Itoa(index), states))
+	}
+	// Read scope of each function, the parents then the node
+	numFuncs, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	var funcNodes []*dne.Node
+	for index := 0; index < numFuncs; index++ {
+		size, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		var scope []string
+		for count := 0; count < size; count++ {
+			variable, err := nextInt()
+			if err != nil {
+				return nil, err
+			}
+			if variable >= numVars {
+				return nil, fmt.Errorf("function %d has undefined variable %d", index, variable)
+			}
+			scope = append(scope, net.Nodes[variable].Name)
+		}
+		if size == 0 {
+			return nil, fmt.Errorf("function %d has empty scope", index)
+		}
+		node := net.NodeNamed(scope[size-1])
+		node.Parents = scope[:size-1]
+		funcNodes = append(funcNodes, node)
+	}
+	// Read table of each function, the last variable varying fastest
+	var probs = make(map[*dne.Node][]float64)
+	for _, node := range funcNodes {
+		count, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		for entry := 0; entry < count; entry++ {
+			if pos >= len(fields) {
+				return nil, fmt.Errorf("unexpected end of file")
+			}
+			prob, err := strconv.ParseFloat(fields[pos], 64)
+			if err != nil {
+				return nil, err
+			}
+			probs[node] = append(probs[node], prob)
+			pos++
+		}
+	}
+	return net, setProbs(net, probs)
+}
+
+// writeUAI writes net to w as a UAI file of a BAYES network with one function per node, in node order.
+func writeUAI(w io.Writer, net *dne.Net) error {
+	if err := checkNet(net); err != nil {
+		return fmt.Errorf("In function convert.writeUAI: %v", err)
+	}
+	var indexes = make(map[string]int)
+	var cards []string
+	for index, node := range net.Nodes {
+		indexes[node.Name] = index
+		cards = append(cards, strconv.Itoa(node.NumStates()))
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "BAYES\n%d\n%s\n%d\n", len(net.Nodes), strings.Join(cards, " "), len(net.Nodes))
+	for _, node := range net.Nodes {
+		scope := []string{strconv.Itoa(len(node.Parents) + 1)}
+		for _, parent := range node.Parents {
+			scope = append(scope, strconv.Itoa(indexes[parent]))
+		}
+		fmt.Fprintf(out, "%s %d\n", strings.Join(scope, " "), indexes[node.Name])
+	}
+	for _, node := range net.Nodes {
+		fmt.Fprintf(out, "\n%d\n", len(node.Probs)*node.NumStates())
+		for _, row := range node.Probs {
+			fmt.Fprintf(out, " %s\n", joinFloats(row, " "))
+		}
+	}
+	return out.Flush()
+}
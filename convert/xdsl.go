@@ -0,0 +1,199 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/slee21/gonetica/dne"
+)
+
+// xdslFile is a GeNIe .xdsl file.
+type xdslFile struct {
+	XMLName     xml.Name       `xml:"smile"`
+	Version     string         `xml:"version,attr"`
+	ID          string         `xml:"id,attr"`
+	NumSamples  int            `xml:"numsamples,attr,omitempty"`
+	DiscSamples int            `xml:"discsamples,attr,omitempty"`
+	Nodes       xdslNodes      `xml:"nodes"`
+	Extensions  xdslExtensions `xml:"extensions"`
+}
+
+// xdslNodes holds the nodes of an .xdsl file, each element named after the kind of node.
+type xdslNodes struct {
+	Nodes []xdslNode `xml:",any"`
+}
+
+// xdslNode is a cpt node with probabilities, the node state varying fastest then the last parent state,
+// or a deterministic node with the resulting state for each combination of parent states.
+type xdslNode struct {
+	XMLName         xml.Name
+	ID              string      `xml:"id,attr"`
+	States          []xdslState `xml:"state"`
+	Parents         string      `xml:"parents,omitempty"`
+	Probabilities   string      `xml:"probabilities,omitempty"`
+	ResultingStates string      `xml:"resultingstates,omitempty"`
+}
+
+// xdslState is a state of an .xdsl node.
+type xdslState struct {
+	ID string `xml:"id,attr"`
+}
+
+// xdslExtensions holds the GeNIe display information of an .xdsl file.
+type xdslExtensions struct {
+	Genie xdslGenie `xml:"genie"`
+}
+
+// xdslGenie is the display information of the network and its nodes.
+type xdslGenie struct {
+	Version string          `xml:"version,attr"`
+	App     string          `xml:"app,attr"`
+	Name    string          `xml:"name,attr"`
+	Comment string          `xml:"comment,omitempty"`
+	Nodes   []xdslGenieNode `xml:"node"`
+}
+
+// xdslGenieNode is the display information of a node, with position as left, top, right and bottom.
+type xdslGenieNode struct {
+	ID       string    `xml:"id,attr"`
+	Name     string    `xml:"name"`
+	Interior xdslColor `xml:"interior"`
+	Outline  xdslColor `xml:"outline"`
+	Font     xdslFont  `xml:"font"`
+	Position string    `xml:"position"`
+	Comment  string    `xml:"comment,omitempty"`
+}
+
+// xdslColor is a colour of a node.
+type xdslColor struct {
+	Color string `xml:"color,attr"`
+}
+
+// xdslFont is the font of a node.
+type xdslFont struct {
+	Color string `xml:"color,attr"`
+	Name  string `xml:"name,attr"`
+	Size  int    `xml:"size,attr"`
+}
+
+// readXDSL reads a GeNIe .xdsl file from r.
+func readXDSL(r io.Reader) (*dne.Net, error) {
+	var file xdslFile
+	if err := newXMLDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("In function convert.readXDSL: %v", err)
+	}
+	net := &dne.Net{Name: file.ID, Comment: file.Extensions.Genie.Comment}
+	var probs = make(map[*dne.Node][]float64)
+	var results = make(map[*dne.Node][]string)
+	for _, element := range file.Nodes.Nodes {
+		kind := element.XMLName.Local
+		if kind != "cpt" && kind != "deterministic" {
+			return nil, fmt.Errorf("In function convert.readXDSL: node %s is a %s node, only cpt and deterministic nodes are supported", element.ID, kind)
+		}
+		var states []string
+		for _, state := range element.States {
+			states = append(states, state.ID)
+		}
+		node := newNode(element.ID, states)
+		node.Parents = strings.Fields(element.Parents)
+		if kind == "deterministic" {
+			results[node] = strings.Fields(element.ResultingStates)
+		} else {
+			table, err := parseFloats(element.Probabilities)
+			if err != nil {
+				return nil, fmt.Errorf("In function convert.readXDSL: node %s: %v", node.Name, err)
+			}
+			probs[node] = table
+		}
+		net.Nodes = append(net.Nodes, node)
+	}
+	// Convert resulting states of deterministic nodes into probabilities of one
+	for node, states := range results {
+		for _, state := range states {
+			index := indexOf(node.States, state)
+			if index < 0 {
+				return nil, fmt.Errorf("In function convert.readXDSL: node %s has no state %s", node.Name, state)
+			}
+			row := make([]float64, len(node.States))
+			row[index] = 1
+			probs[node] = append(probs[node], row...)
+		}
+	}
+	// Read titles, comments and positions as center of node
+	for _, genie := range file.Extensions.Genie.Nodes {
+		node := net.NodeNamed(genie.ID)
+		if node == nil {
+			continue
+		}
+		node.Title, node.Comment = genie.Name, genie.Comment
+		var left, top, right, bottom float64
+		if _, err := fmt.Sscan(genie.Position, &left, &top, &right, &bottom); err == nil {
+			node.Visual = &dne.Visual{X: (left + right) / 2, Y: (top + bottom) / 2}
+		}
+	}
+	if err := setProbs(net, probs); err != nil {
+		return nil, fmt.Errorf("In function convert.readXDSL: %v", err)
+	}
+	return net, nil
+}
+
+// writeXDSL writes net to w as a GeNIe .xdsl file of cpt nodes.
+func writeXDSL(w io.Writer, net *dne.Net) error {
+	if err := checkNet(net); err != nil {
+		return fmt.Errorf("In function convert.writeXDSL: %v", err)
+	}
+	file := xdslFile{Version: "1.0", ID: net.Name, NumSamples: 10000, DiscSamples: 10000}
+	file.Extensions.Genie = xdslGenie{Version: "1.0", App: "gonetica", Name: net.Name, Comment: net.Comment}
+	for _, node := range net.Nodes {
+		element := xdslNode{XMLName: xml.Name{Local: "cpt"}, ID: node.Name, Parents: strings.Join(node.Parents, " "), Probabilities: joinFloats(flatten(node), " ")}
+		for _, state := range stateNames(node) {
+			element.States = append(element.States, xdslState{state})
+		}
+		file.Nodes.Nodes = append(file.Nodes.Nodes, element)
+		// Write display information, centering a node of default size on its position
+		title := node.Title
+		if title == "" {
+			title = node.Name
+		}
+		x, y := 0.0, 0.0
+		if node.Visual != nil {
+			x, y = node.Visual.X, node.Visual.Y
+		}
+		file.Extensions.Genie.Nodes = append(file.Extensions.Genie.Nodes, xdslGenieNode{
+			ID:       node.Name,
+			Name:     title,
+			Interior: xdslColor{"e5f6f7"},
+			Outline:  xdslColor{"000080"},
+			Font:     xdslFont{"000000", "Arial", 8},
+			Position: fmt.Sprintf("%d %d %d %d", int(x)-36, int(y)-18, int(x)+36, int(y)+18),
+			Comment:  node.Comment,
+		})
+	}
+	// Write XML declaration and indented file
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(file); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
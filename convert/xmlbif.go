@@ -0,0 +1,135 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/slee21/gonetica/dne"
+)
+
+// xmlbifFile is an XMLBIF 0.3 file as written by JavaBayes, Weka and pgmpy.
+type xmlbifFile struct {
+	XMLName xml.Name      `xml:"BIF"`
+	Version string        `xml:"VERSION,attr"`
+	Network xmlbifNetwork `xml:"NETWORK"`
+}
+
+// xmlbifNetwork is the network of an XMLBIF file.
+type xmlbifNetwork struct {
+	Name        string             `xml:"NAME"`
+	Properties  []string           `xml:"PROPERTY"`
+	Variables   []xmlbifVariable   `xml:"VARIABLE"`
+	Definitions []xmlbifDefinition `xml:"DEFINITION"`
+	// Probabilities holds definitions of older XMLBIF versions.
+	Probabilities []xmlbifDefinition `xml:"PROBABILITY"`
+}
+
+// xmlbifVariable is a variable of an XMLBIF network.
+type xmlbifVariable struct {
+	Type       string   `xml:"TYPE,attr"`
+	Name       string   `xml:"NAME"`
+	Outcomes   []string `xml:"OUTCOME"`
+	Properties []string `xml:"PROPERTY"`
+}
+
+// xmlbifDefinition is the probability table of a variable given its parents,
+// with the variable state varying fastest then the last parent state.
+type xmlbifDefinition struct {
+	For   string   `xml:"FOR"`
+	Given []string `xml:"GIVEN"`
+	Table string   `xml:"TABLE"`
+}
+
+// readXMLBIF reads an XMLBIF file from r.
+func readXMLBIF(r io.Reader) (*dne.Net, error) {
+	var file xmlbifFile
+	if err := newXMLDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("In function convert.readXMLBIF: %v", err)
+	}
+	net := &dne.Net{Name: strings.TrimSpace(file.Network.Name)}
+	for _, variable := range file.Network.Variables {
+		if variable.Type != "" && variable.Type != "nature" {
+			return nil, fmt.Errorf("In function convert.readXMLBIF: variable %s is a %s variable, only nature variables are supported", variable.Name, variable.Type)
+		}
+		var states []string
+		for _, outcome := range variable.Outcomes {
+			states = append(states, strings.TrimSpace(outcome))
+		}
+		node := newNode(strings.TrimSpace(variable.Name), states)
+		// Read position property
+		for _, property := range variable.Properties {
+			var x, y float64
+			if _, err := fmt.Sscanf(strings.TrimSpace(property), "position = (%g, %g)", &x, &y); err == nil {
+				node.Visual = &dne.Visual{X: x, Y: y}
+			}
+		}
+		net.Nodes = append(net.Nodes, node)
+	}
+	var probs = make(map[*dne.Node][]float64)
+	for _, definition := range append(file.Network.Definitions, file.Network.Probabilities...) {
+		node := net.NodeNamed(strings.TrimSpace(definition.For))
+		if node == nil {
+			return nil, fmt.Errorf("In function convert.readXMLBIF: definition for undefined variable %s", definition.For)
+		}
+		node.Parents = nil
+		for _, given := range definition.Given {
+			node.Parents = append(node.Parents, strings.TrimSpace(given))
+		}
+		table, err := parseFloats(definition.Table)
+		if err != nil {
+			return nil, fmt.Errorf("In function convert.readXMLBIF: variable %s: %v", node.Name, err)
+		}
+		probs[node] = table
+	}
+	if err := setProbs(net, probs); err != nil {
+		return nil, fmt.Errorf("In function convert.readXMLBIF: %v", err)
+	}
+	return net, nil
+}
+
+// writeXMLBIF writes net to w as an XMLBIF 0.3 file.
+func writeXMLBIF(w io.Writer, net *dne.Net) error {
+	if err := checkNet(net); err != nil {
+		return fmt.Errorf("In function convert.writeXMLBIF: %v", err)
+	}
+	file := xmlbifFile{Version: "0.3", Network: xmlbifNetwork{Name: net.Name}}
+	for _, node := range net.Nodes {
+		variable := xmlbifVariable{Type: "nature", Name: node.Name, Outcomes: stateNames(node)}
+		if node.Visual != nil {
+			variable.Properties = []string{fmt.Sprintf("position = (%s, %s)", formatFloat(node.Visual.X), formatFloat(node.Visual.Y))}
+		}
+		file.Network.Variables = append(file.Network.Variables, variable)
+		var table []string
+		for _, prob := range flatten(node) {
+			table = append(table, formatFloat(prob))
+		}
+		file.Network.Definitions = append(file.Network.Definitions, xmlbifDefinition{node.Name, node.Parents, strings.Join(table, " ")})
+	}
+	// Write XML declaration and indented file
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(file); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
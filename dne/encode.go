@@ -0,0 +1,281 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dne
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica"
+)
+
+// header is the comment Netica writes at the start of a .dne file.
+const header = "// ~->[DNET-1]->~\n\n"
+
+// Encode converts a typed Net into the syntax tree of a .dne file, laid out as Netica writes it.
+func Encode(net *Net) (*File, error) {
+	var items []Item
+	if net.Title != "" {
+		items = append(items, newAssign("\n", "title", newString(net.Title)))
+	}
+	if net.Comment != "" {
+		items = append(items, newAssign("\n", "comment", newString(net.Comment)))
+	}
+	if len(net.User) > 0 {
+		items = append(items, newUser("\n\n", "\n\t", net.User))
+	}
+	for _, node := range net.Nodes {
+		block, err := encodeNode(net, node)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, block)
+	}
+	return &File{newBlock(header, "\n", "bnet", net.Name, items), "\n"}, nil
+}
+
+// encodeNode converts a Node of net into a node block.
+func encodeNode(net *Net, node *Node) (*Block, error) {
+	const indent = "\n\t"
+	kind, err := formatKind(node.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("In function dne.Encode: node %s: %v", node.Name, err)
+	}
+	discrete := "TRUE"
+	if !node.Discrete {
+		discrete = "FALSE"
+	}
+	items := []Item{
+		newAssign(indent, "kind", newIdent(kind)),
+		newAssign(indent, "discrete", newIdent(discrete)),
+	}
+	// Write state names if any, otherwise the number of states of discrete nodes
+	if named(node.States) {
+		items = append(items, newAssign(indent, "states", newIdentList(node.States)))
+	} else if node.Discrete && len(node.States) > 0 {
+		items = append(items, newAssign(indent, "numstates", newNumber(float64(len(node.States)))))
+	}
+	if named(node.StateTitles) {
+		items = append(items, newAssign(indent, "statetitles", newStringList(node.StateTitles)))
+	}
+	if len(node.Levels) > 0 {
+		items = append(items, newAssign(indent, "levels", newNumberList(node.Levels)))
+	}
+	if len(node.Inputs) > 0 {
+		items = append(items, newAssign(indent, "inputs", newIdentList(node.Inputs)))
+	}
+	items = append(items, newAssign(indent, "parents", newIdentList(node.Parents)))
+	if len(node.Probs) > 0 {
+		probs, err := encodeProbs(net, node)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, newAssign(indent, "probs", probs))
+	}
+	if node.Equation != "" {
+		items = append(items, newAssign(indent, "equation", newString(node.Equation)))
+	}
+	if node.Title != "" {
+		items = append(items, newAssign(indent, "title", newString(node.Title)))
+	}
+	if node.Comment != "" {
+		items = append(items, newAssign(indent, "comment", newString(node.Comment)))
+	}
+	if len(node.User) > 0 {
+		items = append(items, newUser(indent, indent+"\t", node.User))
+	}
+	if node.Visual != nil {
+		visual := []Item{newAssign(indent+"\t", "center", newNumberList([]float64{node.Visual.X, node.Visual.Y}))}
+		if node.Visual.Height != 0 {
+			visual = append(visual, newAssign(indent+"\t", "height", newNumber(float64(node.Visual.Height))))
+		}
+		items = append(items, newBlock(indent, indent+"\t", "visual", "V1", visual))
+	}
+	return newBlock("\n\n", indent, "node", node.Name, items), nil
+}
+
+// encodeProbs nests the probability rows of node in one list per parent, each row on its own line.
+func encodeProbs(net *Net, node *Node) (Expr, error) {
+	var numStates []int
+	rows := 1
+	for _, name := range node.Parents {
+		parent := net.NodeNamed(name)
+		if parent == nil {
+			return nil, fmt.Errorf("In function dne.Encode: parent %s of node %s not defined", name, node.Name)
+		}
+		numStates = append(numStates, parent.NumStates())
+		rows *= parent.NumStates()
+	}
+	if len(node.Probs) != rows {
+		return nil, fmt.Errorf("In function dne.Encode: node %s has %d probability rows but %d parent state combinations", node.Name, len(node.Probs), rows)
+	}
+	var nest func(rows [][]float64, depth int) Expr
+	nest = func(rows [][]float64, depth int) Expr {
+		if depth == len(numStates) {
+			return newNumberList(rows[0])
+		}
+		var elems []Expr
+		size := len(rows) / numStates[depth]
+		for index := 0; index < numStates[depth]; index++ {
+			elems = append(elems, nest(rows[index*size:(index+1)*size], depth+1))
+		}
+		list := newList(elems)
+		for _, elem := range elems[1:] {
+			setSpace(elem, "\n\t\t"+strings.Repeat(" ", depth+1))
+		}
+		return list
+	}
+	probs := nest(node.Probs, 0)
+	if len(numStates) > 0 {
+		setSpace(probs, "\n\t\t")
+	}
+	return probs, nil
+}
+
+// formatKind returns kind as written in a .dne file.
+func formatKind(kind gonetica.NodeKind) (string, error) {
+	switch kind {
+	case gonetica.NatureNode:
+		return "NATURE", nil
+	case gonetica.ConstantNode:
+		return "CONSTANT", nil
+	case gonetica.DecisionNode:
+		return "DECISION", nil
+	case gonetica.UtilityNode:
+		return "UTILITY", nil
+	case gonetica.DisconnectedNode:
+		return "DISCONNECTED", nil
+	}
+	return "", fmt.Errorf("unknown kind %v", kind)
+}
+
+// named returns whether any of names is not empty.
+func named(names []string) bool {
+	for _, name := range names {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// newBlock returns kind name { items }; after space, with closing before the closing brace.
+func newBlock(space string, closing string, kind string, name string, items []Item) *Block {
+	return &Block{Kind: kind, Name: name, Items: items, Space: []string{space, " ", " ", closing, ""}}
+}
+
+// newUser returns a user block of fields sorted by name, with its items and closing brace at indent.
+func newUser(space string, indent string, fields map[string]string) *Block {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var items []Item
+	for _, name := range names {
+		items = append(items, newAssign(indent, name, newString(fields[name])))
+	}
+	return newBlock(space, indent, "user", "U1", items)
+}
+
+// newAssign returns key = value; after space.
+func newAssign(space string, key string, value Expr) *Assign {
+	return &Assign{Key: key, Value: []Expr{value}, Space: []string{space, " ", ""}}
+}
+
+// newList returns a list of elems separated by a comma and space.
+func newList(elems []Expr) *List {
+	var spaces = []string{" "}
+	for index, elem := range elems {
+		if index > 0 {
+			setSpace(elem, " ")
+			spaces = append(spaces, "")
+		} else {
+			setSpace(elem, "")
+		}
+	}
+	return &List{Elems: elems, Space: append(spaces, "")}
+}
+
+// setSpace sets the space before expr.
+func setSpace(expr Expr, space string) {
+	switch expr := expr.(type) {
+	case *Atom:
+		expr.Space = space
+	case *List:
+		expr.Space[0] = space
+	case *Struct:
+		expr.Space[0] = space
+	}
+}
+
+// newIdent returns an identifier atom.
+func newIdent(text string) *Atom {
+	return &Atom{Token{Kind: Ident, Text: text, Space: " "}}
+}
+
+// newString returns a quoted string atom.
+func newString(text string) *Atom {
+	return &Atom{Token{Kind: String, Text: quote(text), Space: " "}}
+}
+
+// newNumber returns a number atom, * for NaN.
+func newNumber(number float64) *Atom {
+	switch {
+	case math.IsNaN(number):
+		return &Atom{Token{Kind: Punct, Text: "*", Space: " "}}
+	case math.IsInf(number, 1):
+		return &Atom{Token{Kind: Number, Text: "INFINITY", Space: " "}}
+	case math.IsInf(number, -1):
+		return &Atom{Token{Kind: Number, Text: "-INFINITY", Space: " "}}
+	}
+	return &Atom{Token{Kind: Number, Text: strconv.FormatFloat(number, 'g', -1, 64), Space: " "}}
+}
+
+// newIdentList returns a list of identifiers.
+func newIdentList(idents []string) *List {
+	var elems []Expr
+	for _, ident := range idents {
+		elems = append(elems, newIdent(ident))
+	}
+	return newList(elems)
+}
+
+// newStringList returns a list of quoted strings.
+func newStringList(texts []string) *List {
+	var elems []Expr
+	for _, text := range texts {
+		elems = append(elems, newString(text))
+	}
+	return newList(elems)
+}
+
+// newNumberList returns a list of numbers.
+func newNumberList(numbers []float64) *List {
+	var elems []Expr
+	for _, number := range numbers {
+		elems = append(elems, newNumber(number))
+	}
+	return newList(elems)
+}
+
+// quote returns text quoted with escapes for quotes, backslashes and line breaks.
+func quote(text string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+	return "\"" + replacer.Replace(text) + "\""
+}
@@ -0,0 +1,176 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dne
+
+import (
+	"fmt"
+)
+
+// TokenKind is the kind of a lexical token of a .dne file.
+type TokenKind int
+
+// Token kinds of a .dne file.
+const (
+	EOF TokenKind = iota
+	Ident
+	Number
+	String
+	Punct
+)
+
+// Token is a lexical token of a .dne file with the line it starts on.
+// Space holds the whitespace and comments preceding the token so the file can be written back unchanged.
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Line  int
+	Space string
+}
+
+// lexer splits the contents of a .dne file into tokens, keeping whitespace and comments as their space.
+type lexer struct {
+	src  []byte
+	pos  int
+	line int
+}
+
+// newLexer returns a lexer at the start of src.
+func newLexer(src []byte) *lexer {
+	return &lexer{src: src, line: 1}
+}
+
+// next returns the next token in src, or an EOF token at the end.
+func (lex *lexer) next() (Token, error) {
+	space := lex.skip()
+	if lex.pos >= len(lex.src) {
+		return Token{EOF, "", lex.line, space}, nil
+	}
+	start, line := lex.pos, lex.line
+	c := lex.src[lex.pos]
+	switch {
+	case c == '"':
+		// Scan string literal up to closing quote, skipping escaped characters
+		lex.pos++
+		for lex.pos < len(lex.src) && lex.src[lex.pos] != '"' {
+			if lex.src[lex.pos] == '\\' {
+				lex.pos++
+			}
+			if lex.pos < len(lex.src) && lex.src[lex.pos] == '\n' {
+				lex.line++
+			}
+			lex.pos++
+		}
+		if lex.pos >= len(lex.src) {
+			return Token{}, fmt.Errorf("In function dne.Parse: unterminated string on line %d", line)
+		}
+		lex.pos++
+		return Token{String, string(lex.src[start:lex.pos]), line, space}, nil
+	case c == '0' && (lex.peek(1) == 'x' || lex.peek(1) == 'X'):
+		// Scan hexadecimal number such as the colors of node sets
+		lex.pos += 2
+		for lex.pos < len(lex.src) && isHexDigit(lex.src[lex.pos]) {
+			lex.pos++
+		}
+		return Token{Number, string(lex.src[start:lex.pos]), line, space}, nil
+	case isNumberStart(c):
+		// Scan number including sign, fraction and exponent
+		lex.pos++
+		for lex.pos < len(lex.src) && isNumberPart(lex.src[lex.pos], lex.src[lex.pos-1]) {
+			lex.pos++
+		}
+		// Scan signed keywords such as -INFINITY as numbers
+		if lex.pos == start+1 && (c == '-' || c == '+') {
+			for lex.pos < len(lex.src) && isIdentPart(lex.src[lex.pos]) {
+				lex.pos++
+			}
+		}
+		return Token{Number, string(lex.src[start:lex.pos]), line, space}, nil
+	case isIdentPart(c):
+		for lex.pos < len(lex.src) && isIdentPart(lex.src[lex.pos]) {
+			lex.pos++
+		}
+		return Token{Ident, string(lex.src[start:lex.pos]), line, space}, nil
+	default:
+		lex.pos++
+		return Token{Punct, string(c), line, space}, nil
+	}
+}
+
+// skip advances past whitespace and comments and returns them.
+func (lex *lexer) skip() string {
+	start := lex.pos
+	for lex.pos < len(lex.src) {
+		switch c := lex.src[lex.pos]; {
+		case c == '\n':
+			lex.line++
+			lex.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			lex.pos++
+		case c == '/' && lex.peek(1) == '/':
+			for lex.pos < len(lex.src) && lex.src[lex.pos] != '\n' {
+				lex.pos++
+			}
+		case c == '/' && lex.peek(1) == '*':
+			lex.pos += 2
+			for lex.pos < len(lex.src) && !(lex.src[lex.pos] == '*' && lex.peek(1) == '/') {
+				if lex.src[lex.pos] == '\n' {
+					lex.line++
+				}
+				lex.pos++
+			}
+			lex.pos += 2
+			if lex.pos > len(lex.src) {
+				lex.pos = len(lex.src)
+			}
+		default:
+			return string(lex.src[start:lex.pos])
+		}
+	}
+	return string(lex.src[start:lex.pos])
+}
+
+// peek returns the byte offset from the current position, or 0 past the end.
+func (lex *lexer) peek(offset int) byte {
+	if lex.pos+offset < len(lex.src) {
+		return lex.src[lex.pos+offset]
+	}
+	return 0
+}
+
+// isNumberStart returns whether c starts a number.
+func isNumberStart(c byte) bool {
+	return c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.'
+}
+
+// isNumberPart returns whether c following prev continues a number.
+func isNumberPart(c byte, prev byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c == '.', c == 'e', c == 'E':
+		return true
+	case c == '-' || c == '+':
+		return prev == 'e' || prev == 'E'
+	}
+	return false
+}
+
+// isHexDigit returns whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// isIdentPart returns whether c may appear in an identifier, including @ of special values such as @imposs.
+func isIdentPart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '@'
+}
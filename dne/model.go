@@ -0,0 +1,418 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dne
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica"
+)
+
+// Net is the typed model of a Bayesnet read from a .dne file.
+type Net struct {
+	Name    string
+	Title   string
+	Comment string
+	User    map[string]string
+	Nodes   []*Node
+}
+
+// Node is the typed model of a node read from a .dne file.
+// A continuous node has levels as interval boundaries, and is discretised if it has any.
+type Node struct {
+	Name        string
+	Title       string
+	Comment     string
+	Kind        gonetica.NodeKind
+	Discrete    bool
+	States      []string
+	StateTitles []string
+	Levels      []float64
+	Parents     []string
+	// Inputs names the parents as referred to in the equation, if different from their names.
+	Inputs   []string
+	Equation string
+	// Probs has one row of state probabilities per combination of parent states,
+	// ordered with the state of the last parent varying fastest. Undefined entries are NaN.
+	Probs  [][]float64
+	User   map[string]string
+	Visual *Visual
+}
+
+// Visual is the display information of a node, from the first visual block of the node.
+type Visual struct {
+	X, Y   float64
+	Height int
+}
+
+// Read parses the contents of r as a .dne file into a typed Net.
+func Read(r io.Reader) (*Net, error) {
+	file, err := ParseReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(file)
+}
+
+// Decode converts the syntax tree of a .dne file into a typed Net.
+func Decode(file *File) (*Net, error) {
+	var probs = make(map[*Node][]float64)
+	net := &Net{Name: file.Net.Name}
+	for _, item := range file.Net.Items {
+		switch item := item.(type) {
+		case *Assign:
+			switch item.Key {
+			case "title":
+				net.Title = stringValue(item)
+			case "comment":
+				net.Comment = stringValue(item)
+			}
+		case *Block:
+			if item.Kind == "user" {
+				net.User = userFields(item)
+			}
+			if item.Kind != "node" {
+				continue
+			}
+			node, flat, err := decodeNode(item)
+			if err != nil {
+				return nil, err
+			}
+			net.Nodes = append(net.Nodes, node)
+			probs[node] = flat
+		}
+	}
+	// Split probabilities into rows now the number of states of each parent is known
+	for _, node := range net.Nodes {
+		if probs[node] == nil {
+			continue
+		}
+		rows := 1
+		for _, name := range node.Parents {
+			parent := net.NodeNamed(name)
+			if parent == nil {
+				return nil, fmt.Errorf("In function dne.Decode: parent %s of node %s not defined", name, node.Name)
+			}
+			rows *= parent.NumStates()
+		}
+		numStates := node.NumStates()
+		if len(probs[node]) != rows*numStates {
+			return nil, fmt.Errorf("In function dne.Decode: node %s has %d probabilities but %d parent state combinations of %d states", node.Name, len(probs[node]), rows, numStates)
+		}
+		for row := 0; row < rows; row++ {
+			node.Probs = append(node.Probs, probs[node][row*numStates:(row+1)*numStates])
+		}
+	}
+	return net, nil
+}
+
+// NodeNamed returns the Node in net with name, or nil if not defined.
+func (net *Net) NodeNamed(name string) *Node {
+	for _, node := range net.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// NumStates returns the number of states of the node, one fewer than its levels for a discretised continuous node.
+func (node *Node) NumStates() int {
+	if !node.Discrete && len(node.Levels) > 0 {
+		return len(node.Levels) - 1
+	}
+	return len(node.States)
+}
+
+// decodeNode converts a node block into a Node and its probabilities in file order.
+// The function table of a deterministic discrete node is converted into probabilities of 0 or 1.
+func decodeNode(block *Block) (*Node, []float64, error) {
+	var probs []float64
+	var functable []string
+	var numStates int
+	var err error
+	node := &Node{Name: block.Name, Kind: gonetica.NatureNode, Discrete: true}
+	for _, item := range block.Items {
+		assign, ok := item.(*Assign)
+		if !ok {
+			// Read user fields and first visual block
+			if inner := item.(*Block); inner.Kind == "user" {
+				node.User = userFields(inner)
+			} else if inner.Kind == "visual" && node.Visual == nil {
+				node.Visual, err = decodeVisual(inner)
+				if err != nil {
+					return nil, nil, fmt.Errorf("In function dne.Decode: node %s line %d: %v", node.Name, inner.Line, err)
+				}
+			}
+			continue
+		}
+		switch assign.Key {
+		case "title":
+			node.Title = stringValue(assign)
+		case "comment":
+			node.Comment = stringValue(assign)
+		case "equation":
+			node.Equation = stringValue(assign)
+		case "kind":
+			node.Kind, err = parseKind(identValue(assign))
+		case "discrete":
+			node.Discrete = identValue(assign) != "FALSE"
+		case "states":
+			node.States = identList(assign)
+		case "statetitles":
+			node.StateTitles = identList(assign)
+		case "numstates":
+			numStates, err = strconv.Atoi(identValue(assign))
+		case "levels":
+			node.Levels, err = numberList(assign)
+		case "parents":
+			node.Parents = identList(assign)
+		case "inputs":
+			node.Inputs = identList(assign)
+		case "probs":
+			probs, err = numberList(assign)
+		case "functable":
+			functable = atomList(assign)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("In function dne.Decode: node %s line %d: %v", node.Name, assign.Line, err)
+		}
+	}
+	// Name unnamed states by index
+	for index := len(node.States); index < numStates; index++ {
+		node.States = append(node.States, "")
+	}
+	// Convert function table into certain probabilities of the state of each row
+	if probs == nil && functable != nil && node.Discrete {
+		for _, name := range functable {
+			row := make([]float64, node.NumStates())
+			index := -1
+			for other, state := range node.States {
+				if state != "" && state == name {
+					index = other
+				}
+			}
+			if index < 0 {
+				return nil, nil, fmt.Errorf("In function dne.Decode: node %s function table value %s is not a state", node.Name, name)
+			}
+			row[index] = 1
+			probs = append(probs, row...)
+		}
+	}
+	return node, probs, nil
+}
+
+// decodeVisual converts a visual block of a node into its Visual.
+func decodeVisual(block *Block) (*Visual, error) {
+	visual := &Visual{}
+	for _, item := range block.Items {
+		assign, ok := item.(*Assign)
+		if !ok {
+			continue
+		}
+		switch assign.Key {
+		case "center":
+			center, err := numberList(assign)
+			if err != nil {
+				return nil, err
+			}
+			if len(center) != 2 {
+				return nil, fmt.Errorf("center has %d coordinates", len(center))
+			}
+			visual.X, visual.Y = center[0], center[1]
+		case "height":
+			height, err := strconv.Atoi(identValue(assign))
+			if err != nil {
+				return nil, err
+			}
+			visual.Height = height
+		}
+	}
+	return visual, nil
+}
+
+// userFields returns the fields of a user block by name.
+func userFields(block *Block) map[string]string {
+	var fields = make(map[string]string)
+	for _, item := range block.Items {
+		if assign, ok := item.(*Assign); ok {
+			fields[assign.Key] = stringValue(assign)
+		}
+	}
+	return fields
+}
+
+// parseKind returns the NodeKind written as kind.
+func parseKind(kind string) (gonetica.NodeKind, error) {
+	switch kind {
+	case "NATURE":
+		return gonetica.NatureNode, nil
+	case "CONSTANT":
+		return gonetica.ConstantNode, nil
+	case "DECISION":
+		return gonetica.DecisionNode, nil
+	case "UTILITY":
+		return gonetica.UtilityNode, nil
+	case "DISCONNECTED":
+		return gonetica.DisconnectedNode, nil
+	}
+	return 0, fmt.Errorf("unknown kind %s", kind)
+}
+
+// stringValue returns the value of assign as an unquoted string.
+func stringValue(assign *Assign) string {
+	if len(assign.Value) != 1 {
+		return ""
+	}
+	atom, ok := assign.Value[0].(*Atom)
+	if !ok {
+		return ""
+	}
+	return unquote(atom.Text)
+}
+
+// identValue returns the value of assign as a single token text.
+func identValue(assign *Assign) string {
+	if len(assign.Value) != 1 {
+		return ""
+	}
+	if atom, ok := assign.Value[0].(*Atom); ok {
+		return atom.Text
+	}
+	return ""
+}
+
+// identList returns the texts of the elements of a list value.
+func identList(assign *Assign) []string {
+	var idents []string
+	if len(assign.Value) != 1 {
+		return nil
+	}
+	if list, ok := assign.Value[0].(*List); ok {
+		for _, elem := range list.Elems {
+			if atom, ok := elem.(*Atom); ok {
+				idents = append(idents, unquote(atom.Text))
+			}
+		}
+	}
+	return idents
+}
+
+// atomList returns the texts of the atoms of a possibly nested list value in order.
+func atomList(assign *Assign) []string {
+	var texts []string
+	var flatten func(Expr)
+	flatten = func(expr Expr) {
+		switch expr := expr.(type) {
+		case *List:
+			for _, elem := range expr.Elems {
+				flatten(elem)
+			}
+		case *Atom:
+			texts = append(texts, unquote(expr.Text))
+		}
+	}
+	for _, expr := range assign.Value {
+		flatten(expr)
+	}
+	return texts
+}
+
+// numberList returns the numbers of a possibly nested list value in order, with * as NaN.
+func numberList(assign *Assign) ([]float64, error) {
+	var numbers []float64
+	var flatten func(Expr) error
+	flatten = func(expr Expr) error {
+		switch expr := expr.(type) {
+		case *List:
+			for _, elem := range expr.Elems {
+				if err := flatten(elem); err != nil {
+					return err
+				}
+			}
+		case *Atom:
+			number, err := parseNumber(expr.Text)
+			if err != nil {
+				return err
+			}
+			numbers = append(numbers, number)
+		}
+		return nil
+	}
+	for _, expr := range assign.Value {
+		if err := flatten(expr); err != nil {
+			return nil, err
+		}
+	}
+	return numbers, nil
+}
+
+// parseNumber parses a number written in a .dne file, including * for undefined, INFINITY and hexadecimal.
+func parseNumber(text string) (float64, error) {
+	switch text {
+	case "*":
+		return math.NaN(), nil
+	case "INFINITY", "+INFINITY":
+		return math.Inf(1), nil
+	case "-INFINITY":
+		return math.Inf(-1), nil
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		value, err := strconv.ParseUint(text[2:], 16, 64)
+		return float64(value), err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// unquote returns text without enclosing quotes and escapes, or text itself if not quoted.
+// As in Netica, a backslash before a line break continues the string on the next line without
+// the line break or its indentation, and \n, \t, \" and \\ stand for a line break, tab, quote and backslash.
+func unquote(text string) string {
+	if len(text) < 2 || !strings.HasPrefix(text, "\"") || !strings.HasSuffix(text, "\"") {
+		return text
+	}
+	body := text[1 : len(text)-1]
+	var buf bytes.Buffer
+	for index := 0; index < len(body); index++ {
+		char := body[index]
+		if char != '\\' || index+1 == len(body) {
+			buf.WriteByte(char)
+			continue
+		}
+		index++
+		switch body[index] {
+		case '\r', '\n':
+			// Skip line break and indentation of the continued line
+			if body[index] == '\r' && index+1 < len(body) && body[index+1] == '\n' {
+				index++
+			}
+			for index+1 < len(body) && (body[index+1] == ' ' || body[index+1] == '\t') {
+				index++
+			}
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		default:
+			buf.WriteByte(body[index])
+		}
+	}
+	return buf.String()
+}
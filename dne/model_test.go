@@ -0,0 +1,37 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dne
+
+import "testing"
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`Smoking`, `Smoking`},
+		{`"Lung Cancer"`, `Lung Cancer`},
+		{`"say \"Asia\"\\"`, `say "Asia"\`},
+		{`"first\nsecond\ttab"`, "first\nsecond\ttab"},
+		// Line continuations are dropped with the indentation of the next line
+		{"\"\\n\\\n\tLauritzen, or \\\n\tnone.\"", "\nLauritzen, or none."},
+		{"\"one \\\r\n  two\"", "one two"},
+	}
+	for _, test := range tests {
+		if got := unquote(test.text); got != test.want {
+			t.Errorf("unquote(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dne parses Netica's .dne text format for Bayesnets without the Netica library.
+//
+// Parse returns the syntax tree of a file as nested blocks of assignments,
+// which Decode converts into a typed model of the network and its nodes.
+// The syntax tree keeps the whitespace and comments around each token, so
+// Write reproduces a parsed file byte for byte, and Encode converts a typed
+// model back into a syntax tree to write.
+package dne
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// File is the syntax tree of a .dne file.
+// End holds the whitespace and comments after the network block.
+type File struct {
+	Net *Block
+	End string
+}
+
+// Block is a braced block such as bnet, node or visual, with its kind, optional name and items.
+// Space holds the whitespace and comments before its kind, name, opening brace, closing brace and semicolon.
+type Block struct {
+	Kind  string
+	Name  string
+	Items []Item
+	Line  int
+	Space []string
+}
+
+// Item is an *Assign or a nested *Block within a Block.
+type Item interface {
+	item()
+}
+
+// Assign is an assignment of a value to a key within a Block, such as states = (a, b).
+// The value is usually a single expression but may be a sequence.
+// Space holds the whitespace and comments before its key, equals sign and semicolon.
+type Assign struct {
+	Key   string
+	Value []Expr
+	Line  int
+	Space []string
+}
+
+// Expr is an *Atom or a *List in the value of an Assign.
+type Expr interface {
+	expr()
+}
+
+// Atom is an identifier, number or quoted string in a value.
+type Atom struct {
+	Token
+}
+
+// List is a parenthesised, comma separated list of values.
+// Space holds the whitespace and comments before its opening parenthesis, each comma and closing parenthesis.
+type List struct {
+	Elems []Expr
+	Space []string
+}
+
+// Struct is a braced list of items in a value, such as the font of nodefont = font {size= 9;}.
+// Space holds the whitespace and comments before its opening and closing braces.
+type Struct struct {
+	Items []Item
+	Space []string
+}
+
+func (*Block) item()  {}
+func (*Assign) item() {}
+func (*Atom) expr()   {}
+func (*List) expr()   {}
+func (*Struct) expr() {}
+
+// parser builds a syntax tree from tokens of a lexer with one token of lookahead.
+type parser struct {
+	lex *lexer
+	tok Token
+}
+
+// Parse parses the contents of a .dne file into its syntax tree.
+func Parse(src []byte) (*File, error) {
+	p := &parser{lex: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	// Parse network block and check nothing follows it
+	block, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.Kind != EOF {
+		return nil, p.errorf("unexpected %q after network", p.tok.Text)
+	}
+	return &File{block, p.tok.Space}, nil
+}
+
+// ParseReader parses the contents of r as a .dne file into its syntax tree.
+func ParseReader(r io.Reader) (*File, error) {
+	// Read contents and check for errors
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(buf)
+}
+
+// advance reads the next token into p.tok.
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// expect checks the current token is the punctuation text, appends its space to spaces and advances past it.
+func (p *parser) expect(text string, spaces *[]string) error {
+	if p.tok.Kind != Punct || p.tok.Text != text {
+		return p.errorf("expected %q but found %q", text, p.tok.Text)
+	}
+	*spaces = append(*spaces, p.tok.Space)
+	return p.advance()
+}
+
+// is returns whether the current token is the punctuation text.
+func (p *parser) is(text string) bool {
+	return p.tok.Kind == Punct && p.tok.Text == text
+}
+
+// errorf returns an error at the line of the current token.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("In function dne.Parse: line %d: %s", p.tok.Line, fmt.Sprintf(format, args...))
+}
+
+// block parses kind [name] { items } ; starting at kind.
+func (p *parser) block() (*Block, error) {
+	if p.tok.Kind != Ident {
+		return nil, p.errorf("expected block but found %q", p.tok.Text)
+	}
+	block := &Block{Kind: p.tok.Text, Line: p.tok.Line, Space: []string{p.tok.Space, ""}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	// Read optional name
+	if p.tok.Kind == Ident || p.tok.Kind == Number {
+		block.Name, block.Space[1] = p.tok.Text, p.tok.Space
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("{", &block.Space); err != nil {
+		return nil, err
+	}
+	items, err := p.items(&block.Space)
+	if err != nil {
+		return nil, err
+	}
+	block.Items = items
+	return block, p.expect(";", &block.Space)
+}
+
+// items parses items up to and including the closing brace of a block or struct, appending its space to spaces.
+func (p *parser) items(spaces *[]string) ([]Item, error) {
+	var items []Item
+	line := p.tok.Line
+	for !p.is("}") {
+		if p.tok.Kind == EOF {
+			return nil, p.errorf("unterminated block starting on line %d", line)
+		}
+		item, err := p.item()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, p.expect("}", spaces)
+}
+
+// item parses an assignment or nested block.
+func (p *parser) item() (Item, error) {
+	if p.tok.Kind != Ident {
+		return nil, p.errorf("expected item but found %q", p.tok.Text)
+	}
+	// Look ahead past key to tell assignments from blocks
+	key, lexState := p.tok, *p.lex
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if !p.is("=") {
+		*p.lex, p.tok = lexState, key
+		return p.block()
+	}
+	assign := &Assign{Key: key.Text, Line: key.Line, Space: []string{key.Space}}
+	if err := p.expect("=", &assign.Space); err != nil {
+		return nil, err
+	}
+	// Read value expressions up to semicolon
+	for !p.is(";") {
+		if p.tok.Kind == EOF {
+			return nil, p.errorf("unterminated value of %s starting on line %d", key.Text, key.Line)
+		}
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		assign.Value = append(assign.Value, expr)
+	}
+	return assign, p.expect(";", &assign.Space)
+}
+
+// expr parses an atom or a parenthesised list.
+func (p *parser) expr() (Expr, error) {
+	switch {
+	case p.is("("):
+		list := &List{}
+		if err := p.expect("(", &list.Space); err != nil {
+			return nil, err
+		}
+		// Read comma separated elements up to closing parenthesis
+		for !p.is(")") {
+			elem, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			list.Elems = append(list.Elems, elem)
+			if p.is(",") {
+				if err := p.expect(",", &list.Space); err != nil {
+					return nil, err
+				}
+			} else if !p.is(")") {
+				return nil, p.errorf("expected \",\" or \")\" but found %q", p.tok.Text)
+			}
+		}
+		return list, p.expect(")", &list.Space)
+	case p.is("{"):
+		strct := &Struct{}
+		if err := p.expect("{", &strct.Space); err != nil {
+			return nil, err
+		}
+		items, err := p.items(&strct.Space)
+		if err != nil {
+			return nil, err
+		}
+		strct.Items = items
+		return strct, nil
+	case p.tok.Kind == Ident || p.tok.Kind == Number || p.tok.Kind == String || p.is("*"):
+		atom := &Atom{p.tok}
+		return atom, p.advance()
+	default:
+		return nil, p.errorf("unexpected %q in value", p.tok.Text)
+	}
+}
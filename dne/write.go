@@ -0,0 +1,118 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dne
+
+import (
+	"bytes"
+	"io"
+)
+
+// printer writes the tokens of a syntax tree with their space.
+type printer struct {
+	buf bytes.Buffer
+}
+
+// Write writes the syntax tree of a .dne file to w.
+// A parsed file is reproduced byte for byte, with its whitespace and comments.
+// Tokens without space, such as those of syntax built in code, are separated by a single space only where needed.
+func Write(w io.Writer, file *File) error {
+	p := &printer{}
+	p.block(file.Net)
+	p.buf.WriteString(file.End)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// Bytes returns the contents of the .dne file with syntax tree file.
+func Bytes(file *File) []byte {
+	var buf bytes.Buffer
+	Write(&buf, file)
+	return buf.Bytes()
+}
+
+// token writes text after space, separating it from the previous token if space is empty and they would merge.
+func (p *printer) token(space string, text string) {
+	if space == "" && p.buf.Len() > 0 && text != "" {
+		last := p.buf.Bytes()[p.buf.Len()-1]
+		if isIdentPart(last) && (isIdentPart(text[0]) || text[0] == '.') {
+			space = " "
+		}
+	}
+	p.buf.WriteString(space)
+	p.buf.WriteString(text)
+}
+
+// block writes kind [name] { items } ;.
+func (p *printer) block(block *Block) {
+	p.token(spaceAt(block.Space, 0), block.Kind)
+	if block.Name != "" {
+		p.token(spaceAt(block.Space, 1), block.Name)
+	}
+	p.token(spaceAt(block.Space, 2), "{")
+	p.items(block.Items)
+	p.token(spaceAt(block.Space, 3), "}")
+	p.token(spaceAt(block.Space, 4), ";")
+}
+
+// items writes assignments and nested blocks.
+func (p *printer) items(items []Item) {
+	for _, item := range items {
+		switch item := item.(type) {
+		case *Block:
+			p.block(item)
+		case *Assign:
+			p.token(spaceAt(item.Space, 0), item.Key)
+			p.token(spaceAt(item.Space, 1), "=")
+			for _, expr := range item.Value {
+				p.expr(expr)
+			}
+			p.token(spaceAt(item.Space, 2), ";")
+		}
+	}
+}
+
+// expr writes an atom, list or struct.
+func (p *printer) expr(expr Expr) {
+	switch expr := expr.(type) {
+	case *Atom:
+		p.token(expr.Space, expr.Text)
+	case *List:
+		// Space holds the opening parenthesis, commas then closing parenthesis
+		commas := len(expr.Space) - 2
+		if commas < 0 {
+			commas = len(expr.Elems) - 1
+		}
+		p.token(spaceAt(expr.Space, 0), "(")
+		for index, elem := range expr.Elems {
+			p.expr(elem)
+			if index < commas {
+				p.token(spaceAt(expr.Space, 1+index), ",")
+			}
+		}
+		p.token(spaceAt(expr.Space, 1+commas), ")")
+	case *Struct:
+		p.token(spaceAt(expr.Space, 0), "{")
+		p.items(expr.Items)
+		p.token(spaceAt(expr.Space, 1), "}")
+	}
+}
+
+// spaceAt returns the space at index, or none for syntax built in code.
+func spaceAt(spaces []string, index int) string {
+	if index < len(spaces) {
+		return spaces[index]
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dne
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestRoundTrip checks Netica-written files in testdata are written back byte for byte.
+func TestRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.dne"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no .dne files in testdata")
+	}
+	for _, path := range paths {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := Parse(src)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		if out := Bytes(file); !bytes.Equal(out, src) {
+			t.Errorf("%s: written back differently from byte %d", path, firstDiff(out, src))
+		}
+		// Check the file also decodes into a model
+		if _, err := Decode(file); err != nil {
+			t.Errorf("%s: %s", path, err)
+		}
+	}
+}
+
+// TestDecodeEncode checks the model of asia.dne is unchanged when encoded and decoded again.
+func TestDecodeEncode(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("testdata", "asia.dne"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := Read(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := "\nLauritzen & Spiegelhalter \"Asia\" example.\nShortness-of-breath (dyspnea) may be due to tuberculosis, lung cancer or bronchitis, or none of them, or more than one of them."
+	if net.Comment != comment {
+		t.Errorf("comment %q, want %q", net.Comment, comment)
+	}
+	// Encode and decode twice so escapes added by each pass would show
+	decoded := net
+	for pass := 1; pass <= 2; pass++ {
+		file, err := Encode(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err = Read(bytes.NewReader(Bytes(file)))
+		if err != nil {
+			t.Fatalf("pass %d: %s", pass, err)
+		}
+		if !reflect.DeepEqual(decoded, net) {
+			t.Errorf("pass %d: decoded model differs", pass)
+			compareModels(t, decoded, net)
+		}
+	}
+}
+
+// compareModels reports the titles, comments and probabilities of got that differ from want.
+func compareModels(t *testing.T, got *Net, want *Net) {
+	if got.Title != want.Title || got.Comment != want.Comment {
+		t.Errorf("net title %q comment %q, want %q %q", got.Title, got.Comment, want.Title, want.Comment)
+	}
+	if len(got.Nodes) != len(want.Nodes) {
+		t.Errorf("%d nodes, want %d", len(got.Nodes), len(want.Nodes))
+		return
+	}
+	for index, node := range got.Nodes {
+		other := want.Nodes[index]
+		if node.Title != other.Title || node.Comment != other.Comment {
+			t.Errorf("node %s title %q comment %q, want %q %q", node.Name, node.Title, node.Comment, other.Title, other.Comment)
+		}
+		if !reflect.DeepEqual(node.Probs, other.Probs) {
+			t.Errorf("node %s probs %v, want %v", node.Name, node.Probs, other.Probs)
+		}
+	}
+}
+
+// TestHexNumber checks hexadecimal numbers such as node set colors are single tokens.
+func TestHexNumber(t *testing.T) {
+	lex := newLexer([]byte("Color = 0x00E1E1E1;"))
+	var texts []string
+	for {
+		tok, err := lex.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Kind == EOF {
+			break
+		}
+		texts = append(texts, tok.Text)
+	}
+	if len(texts) != 4 || texts[2] != "0x00E1E1E1" {
+		t.Errorf("got tokens %q", texts)
+	}
+	if value, err := parseNumber("0x00E1E1E1"); err != nil || value != 0xE1E1E1 {
+		t.Errorf("parseNumber: got %g, %v", value, err)
+	}
+}
+
+// firstDiff returns the offset of the first byte differing between a and b.
+func firstDiff(a []byte, b []byte) int {
+	for index := range a {
+		if index >= len(b) || a[index] != b[index] {
+			return index
+		}
+	}
+	return len(a)
+}
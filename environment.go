@@ -12,6 +12,9 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
 package gonetica
 
 /*
@@ -44,7 +47,19 @@ type Environment struct {
 	c    *C.environ_ns
 	cMsg *C.char
 
-	netlocks map[*C.net_bn]*sync.RWMutex
+	netstates map[*C.net_bn]*netState
+}
+
+// netState is the Go side state of a Netica network.
+type netState struct {
+	sync.RWMutex
+
+	// stale is set when tables or structure changed since the network was last compiled.
+	stale bool
+	// updated is set when beliefs were last queried with the current findings and tables.
+	updated bool
+	// findings is set when findings may have been entered since all findings were last retracted.
+	findings bool
 }
 
 // NewEnvironment returns a new initialised Environment with optional license string.
@@ -70,7 +85,7 @@ func NewEnvironment(license string) (*Environment, error) {
 	}
 	C.ArgumentChecking_ns(C.QUICK_CHECK, env.c)
 	// Initialise synchronisation map
-	env.netlocks = make(map[*C.net_bn]*sync.RWMutex)
+	env.netstates = make(map[*C.net_bn]*netState)
 	return env, nil
 }
 
@@ -107,12 +122,24 @@ func (env *Environment) Message() string {
 	return C.GoString(env.cMsg)
 }
 
+// SetCaseFileDelimiter sets the character separating columns of case files written by Netica, such as ',' or '\t'.
+func (env *Environment) SetCaseFileDelimiter(delim rune) error {
+	C.SetCaseFileDelimChar_ns(C.int(delim), env.c)
+	return env.Errors()
+}
+
+// SetMissingDataChar sets the character marking missing values in case files written by Netica, '*' by default.
+func (env *Environment) SetMissingDataChar(missing rune) error {
+	C.SetMissingDataChar_ns(C.int(missing), env.c)
+	return env.Errors()
+}
+
 // NetworkList returns a Slice of Networks in the order they were read.
 func (env *Environment) NetworkList() ([]*Network, error) {
 	var networks []*Network
 	// Iterate over Netica nets and save them as Network in networks
 	for index := C.int(0); C.GetNthNet_bn(index, env.c) != nil; index++ {
-		networks = append(networks, &Network{C.GetNthNet_bn(index, env.c), env})
+		networks = append(networks, &Network{c: C.GetNthNet_bn(index, env.c), env: env})
 	}
 	// Check for errors
 	if err := env.Errors(); err != nil {
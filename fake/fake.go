@@ -0,0 +1,347 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake provides an in-memory gonetica Backend for testing code that serves Bayesnets,
+// such as the gncli server handlers, on machines without the Netica library.
+//
+// Networks are registered by file name and have fixed prior beliefs. Entering a state finding
+// sets the beliefs of that node to the state, other nodes keep their priors.
+package fake
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/slee21/gonetica"
+)
+
+// Backend is an in-memory gonetica.Backend loading copies of Networks registered by file name.
+type Backend struct {
+	mu       sync.Mutex
+	networks map[string]*Network
+}
+
+// Network is an in-memory Bayesnet with fixed prior beliefs.
+type Network struct {
+	sync.RWMutex
+
+	NetName    string
+	NetTitle   string
+	NetComment string
+	Nodes      []*Node
+
+	findings map[string]int
+}
+
+// Node is a node of a Network with fixed prior beliefs.
+// A node without states is continuous and its findings are real values.
+type Node struct {
+	NodeName    string
+	NodeTitle   string
+	NodeComment string
+	NodeKind    gonetica.NodeKind
+	States      []string
+	Levels      []float64
+	Parents     []string
+	Beliefs     []float64
+	// Table is the conditional probability table returned by CPT, nil if none.
+	Table [][]float64
+
+	net   *Network
+	value *float64
+}
+
+// NewBackend returns a new Backend with no Networks registered.
+func NewBackend() *Backend {
+	return &Backend{networks: make(map[string]*Network)}
+}
+
+// Add registers net to be loaded from files with name.
+func (backend *Backend) Add(name string, net *Network) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	backend.networks[name] = net
+}
+
+// Load returns a copy of the Network registered with name, ignoring buf and options.
+func (backend *Backend) Load(name string, buf []byte, options gonetica.LoadOptions) (gonetica.BackendNetwork, error) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	net, ok := backend.networks[name]
+	if !ok {
+		return nil, fmt.Errorf("In function Backend.Load: no network registered for file %s", name)
+	}
+	loaded := NewNetwork(net.NetName, net.Nodes...)
+	loaded.NetTitle, loaded.NetComment = net.NetTitle, net.NetComment
+	return loaded, nil
+}
+
+// NewNetwork returns a new Network with name holding copies of nodes.
+func NewNetwork(name string, nodes ...*Node) *Network {
+	net := &Network{NetName: name, findings: make(map[string]int)}
+	for _, node := range nodes {
+		copied := *node
+		copied.net = net
+		copied.value = nil
+		net.Nodes = append(net.Nodes, &copied)
+	}
+	return net
+}
+
+// Name returns the name of the Network.
+func (net *Network) Name() string {
+	return net.NetName
+}
+
+// Title returns the title of the Network.
+func (net *Network) Title() string {
+	return net.NetTitle
+}
+
+// Comment returns the comment of the Network.
+func (net *Network) Comment() string {
+	return net.NetComment
+}
+
+// NodeList returns a Slice of Nodes sorted by name lexicographically ascending.
+func (net *Network) NodeList() ([]gonetica.BackendNode, error) {
+	var nodes []gonetica.BackendNode
+	for _, node := range net.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name() < nodes[j].Name()
+	})
+	return nodes, nil
+}
+
+// NodeNamed returns Node in net with name.
+func (net *Network) NodeNamed(name string) (gonetica.BackendNode, error) {
+	node := net.node(name)
+	if node == nil {
+		return nil, fmt.Errorf("In function Network.NodeNamed: node %s not defined for network %s", name, net.NetName)
+	}
+	return node, nil
+}
+
+// node returns Node in net with name, or nil if not found.
+func (net *Network) node(name string) *Node {
+	for _, node := range net.Nodes {
+		if node.NodeName == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// EnterCase enters a state name, #index or real value finding for each node named in caseMap,
+// skipping unknown nodes. All findings are retracted on error.
+func (net *Network) EnterCase(caseMap map[string]string) error {
+	for name, evidence := range caseMap {
+		node := net.node(name)
+		if node == nil {
+			continue
+		}
+		if err := node.enterFinding(evidence); err != nil {
+			net.ClearCases()
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearCases retracts all findings in the network.
+func (net *Network) ClearCases() error {
+	net.findings = make(map[string]int)
+	for _, node := range net.Nodes {
+		node.value = nil
+	}
+	return nil
+}
+
+// FindingsProbability returns the product of the prior belief of each state finding, as if nodes were independent.
+func (net *Network) FindingsProbability() (float64, error) {
+	prob := 1.0
+	for name, state := range net.findings {
+		if beliefs := net.node(name).Beliefs; state < len(beliefs) {
+			prob *= beliefs[state]
+		}
+	}
+	return prob, nil
+}
+
+// CloseNetwork closes the Network, which does nothing.
+func (net *Network) CloseNetwork() error {
+	return nil
+}
+
+// Name returns the name of the Node.
+func (node *Node) Name() string {
+	return node.NodeName
+}
+
+// Title returns the title of the Node.
+func (node *Node) Title() string {
+	return node.NodeTitle
+}
+
+// Comment returns the comment of the Node.
+func (node *Node) Comment() string {
+	return node.NodeComment
+}
+
+// Kind returns the kind of the Node, nature unless set.
+func (node *Node) Kind() gonetica.NodeKind {
+	if node.NodeKind == 0 {
+		return gonetica.NatureNode
+	}
+	return node.NodeKind
+}
+
+// IsDiscreteType returns bool whether node has states.
+func (node *Node) IsDiscreteType() bool {
+	return len(node.States) > 0
+}
+
+// StateNameList returns a Slice of state names in order.
+func (node *Node) StateNameList() ([]string, error) {
+	return node.States, nil
+}
+
+// LevelList returns a Slice of level floats in order.
+func (node *Node) LevelList() ([]float64, error) {
+	return node.Levels, nil
+}
+
+// ParentList returns a Slice of parent Nodes in order.
+func (node *Node) ParentList() ([]gonetica.BackendNode, error) {
+	var parents []gonetica.BackendNode
+	for _, name := range node.Parents {
+		parent, err := node.net.NodeNamed(name)
+		if err != nil {
+			return nil, err
+		}
+		parents = append(parents, parent)
+	}
+	return parents, nil
+}
+
+// ChildList returns a Slice of child Nodes in order of the Network.
+func (node *Node) ChildList() ([]gonetica.BackendNode, error) {
+	var children []gonetica.BackendNode
+	for _, other := range node.net.Nodes {
+		for _, name := range other.Parents {
+			if name == node.NodeName {
+				children = append(children, other)
+				break
+			}
+		}
+	}
+	return children, nil
+}
+
+// ParseState returns index of state given as #index or state name.
+func (node *Node) ParseState(state string) (int, error) {
+	// Try to parse state as state index
+	if strings.HasPrefix(state, "#") {
+		index, err := strconv.Atoi(strings.TrimPrefix(state, "#"))
+		if err == nil && index >= 0 && index < len(node.States) {
+			return index, nil
+		}
+	}
+	// Try to lookup state by name
+	for index, name := range node.States {
+		if name == state {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("In function Node.ParseState: state %s not defined for node %s", state, node.NodeName)
+}
+
+// enterFinding enters evidence as a state for a discrete node or a real value for a continuous node.
+func (node *Node) enterFinding(evidence string) error {
+	if !node.IsDiscreteType() {
+		value, err := strconv.ParseFloat(evidence, 64)
+		if err != nil {
+			return err
+		}
+		node.value = &value
+		return nil
+	}
+	index, err := node.ParseState(evidence)
+	if err != nil {
+		return err
+	}
+	node.net.findings[node.NodeName] = index
+	return nil
+}
+
+// BeliefList returns Slice of belief floats in order of states,
+// certain of the state entered as finding and the prior beliefs otherwise.
+func (node *Node) BeliefList() ([]float64, error) {
+	state, ok := node.net.findings[node.NodeName]
+	if !ok {
+		return node.Beliefs, nil
+	}
+	beliefs := make([]float64, len(node.States))
+	beliefs[state] = 1
+	return beliefs, nil
+}
+
+// CPT returns the conditional probability table of the node, or nil if it has none.
+func (node *Node) CPT() ([][]float64, error) {
+	return node.Table, nil
+}
+
+// Value returns the expected value and standard deviation of node, the real value entered for a continuous node
+// or over the levels of a discrete node.
+func (node *Node) Value() (float64, float64, error) {
+	if node.value != nil {
+		return *node.value, 0, nil
+	}
+	if len(node.Levels) != len(node.States) || len(node.Levels) == 0 {
+		return 0, 0, fmt.Errorf("%s: %s", "In function Node.Value", "undefined expected value")
+	}
+	beliefs, _ := node.BeliefList()
+	var mean, square float64
+	for index, level := range node.Levels {
+		mean += beliefs[index] * level
+		square += beliefs[index] * level * level
+	}
+	return mean, math.Sqrt(math.Max(square-mean*mean, 0)), nil
+}
+
+// Infer returns the expected value of the node if defined, or its most likely state.
+func (node *Node) Infer() (string, error) {
+	// Try to return a real value estimate
+	if value, _, err := node.Value(); err == nil {
+		return strconv.FormatFloat(value, 'E', -1, 64), nil
+	}
+	if !node.IsDiscreteType() {
+		return "", fmt.Errorf("In function Node.Infer: node %s has no states or value", node.NodeName)
+	}
+	// Return first state with max belief
+	beliefs, _ := node.BeliefList()
+	maxIndex := 0
+	for index, belief := range beliefs {
+		if belief > beliefs[maxIndex] {
+			maxIndex = index
+		}
+	}
+	return node.States[maxIndex], nil
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/slee21/gonetica/convert"
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert in out",
+	Short: "Convert a Bayesnet between file formats",
+	Long: `Convert reads a Bayesnet file and writes it in another file format, each
+detected by file extension: Netica .dne, XMLBIF .xmlbif or .xml, BIF .bif,
+Hugin .net, GeNIe .xdsl and UAI .uai. Names that are not valid in Netica are
+replaced, keeping the original names as titles. Only discrete nature nodes
+can be written to formats other than .dne. The function table of a deterministic
+node is written as probabilities of 0 or 1, but a node with an equation and no
+table cannot be written until its equation is converted into a table.`,
+	RunE: runConvert,
+}
+
+func init() {
+	RootCmd.AddCommand(convertCmd)
+}
+
+// runConvert reads the Bayesnet file in args[0] and writes it to args[1].
+func runConvert(cmd *cobra.Command, args []string) error {
+	// Check arguments
+	if len(args) != 2 {
+		return fmt.Errorf("In function convert: input and output files are required")
+	}
+	// Read Bayesnet and check for errors
+	buf, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	net, err := convert.Read(args[0], buf)
+	if err != nil {
+		return err
+	}
+	if convert.ForPath(args[1]) == nil {
+		return fmt.Errorf("In function convert: unsupported file format of %s", args[1])
+	}
+	// Write Bayesnet and check for errors
+	out, err := os.Create(args[1])
+	if err != nil {
+		return err
+	}
+	if err := convert.Write(out, args[1], net); err != nil {
+		out.Close()
+		os.Remove(args[1])
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,247 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/slee21/gonetica"
+)
+
+var (
+	inferNet     string
+	inferCases   string
+	inferTargets []string
+	inferOut     string
+	inferDelim   string
+	inferMissing string
+	inferFail    bool
+)
+
+// inferCmd represents the batch inference command
+var inferCmd = &cobra.Command{
+	Use:   "infer",
+	Short: "Perform Bayesian inference on each case of a case file",
+	Long: `Infer reads a comma or tab delimited Netica case file, performs Bayesian
+inference on each case for the target nodes and writes the case file with the
+belief of each target state appended as P(node=state) columns. Cases are
+streamed so files of any size can be scored without running a server.
+Cases that cannot be read are logged and skipped, cases with inconsistent
+findings are written with missing beliefs, unless --fail-fast is set.`,
+	RunE: runInfer,
+}
+
+func init() {
+	RootCmd.AddCommand(inferCmd)
+
+	// Initialise flags
+	inferCmd.Flags().StringVar(&inferNet, "net", "", "Netica Bayesnet file to perform inference with")
+	inferCmd.Flags().StringVar(&inferCases, "cases", "", "comma or tab delimited Netica case file to read")
+	inferCmd.Flags().StringSliceVar(&inferTargets, "targets", nil, "comma separated names of target nodes")
+	inferCmd.Flags().StringVar(&inferOut, "out", "", "case file to write (default stdout)")
+	inferCmd.Flags().StringVar(&inferDelim, "delim", "", "column delimiter, comma or tab (default comma for .csv files, tab otherwise)")
+	inferCmd.Flags().StringVar(&inferMissing, "missing", "*", "character marking missing values")
+	inferCmd.Flags().BoolVar(&inferFail, "fail-fast", false, "stop at the first case that cannot be read or inferred")
+}
+
+// runInfer performs Bayesian inference on each case of a case file and writes the results.
+func runInfer(cmd *cobra.Command, args []string) error {
+	// Check flags
+	if inferNet == "" || inferCases == "" || len(inferTargets) == 0 {
+		return fmt.Errorf("In function infer: --net, --cases and --targets are required")
+	}
+	delim, err := parseDelim(inferDelim, inferCases)
+	if err != nil {
+		return err
+	}
+	if len([]rune(inferMissing)) != 1 {
+		return fmt.Errorf("In function infer: missing must be a single character")
+	}
+	// Initialise Netica with case file format and check for errors
+	err = initNetica(viper.GetString("license"))
+	if err != nil {
+		return err
+	}
+	defer neticaEnv.CloseEnvironment()
+	err = neticaEnv.SetCaseFileDelimiter(delim)
+	if err != nil {
+		return err
+	}
+	err = neticaEnv.SetMissingDataChar([]rune(inferMissing)[0])
+	if err != nil {
+		return err
+	}
+	// Read Bayesnet and check for errors
+	net, err := gonetica.NewNetwork(neticaEnv, inferNet)
+	if err != nil {
+		return err
+	}
+	defer net.CloseNetwork()
+	// Lookup target nodes and their states and check for errors
+	var targets []*gonetica.Node
+	var columns []string
+	for _, name := range inferTargets {
+		node, err := net.NodeNamed(name)
+		if err != nil {
+			return err
+		}
+		states, err := node.StateNameList()
+		if err != nil {
+			return err
+		}
+		for index := range states {
+			columns = append(columns, fmt.Sprintf("P(%s=%s)", name, stateLabel(states, index)))
+		}
+		targets = append(targets, node)
+	}
+	// Lookup nodes in case file header and check for errors
+	nodes, err := caseFileNodes(net, inferCases, delim)
+	if err != nil {
+		return err
+	}
+	// Open case file and check for errors
+	reader, err := gonetica.NewCaseReader(net, inferCases, nodes)
+	if err != nil {
+		return err
+	}
+	defer reader.CloseCaseReader()
+	// Open output file and check for errors
+	var out io.Writer = os.Stdout
+	if inferOut != "" {
+		file, err := os.Create(inferOut)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+	// Iterate over cases writing findings and beliefs, logging and skipping failed cases unless failing fast
+	sep := string(delim)
+	var header bool
+	var failed int
+	for index := 0; ; index++ {
+		id, freq, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			var head, row string
+			head, row, err = net.FormatCase(nodes, id, freq)
+			if err == nil {
+				if !header {
+					fmt.Fprintln(writer, head+sep+strings.Join(columns, sep))
+					header = true
+				}
+				err = writeBeliefs(writer, row, sep, targets, len(columns))
+			}
+		}
+		if err != nil {
+			// Case files have a header line followed by one line per case
+			err = fmt.Errorf("In function infer: case %d on line %d: %s", index, index+2, err)
+			if inferFail {
+				writer.Flush()
+				return err
+			}
+			log.Println(err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Printf("%d cases failed\n", failed)
+	}
+	return writer.Flush()
+}
+
+// writeBeliefs writes row followed by the belief of each state of targets given the findings entered.
+// If the findings are inconsistent, the row is written with width missing beliefs unless failing fast.
+func writeBeliefs(writer io.Writer, row string, sep string, targets []*gonetica.Node, width int) error {
+	// Infer beliefs of each target and check for errors
+	var beliefs []string
+	for _, node := range targets {
+		beliefList, err := node.BeliefList()
+		if err != nil {
+			if !inferFail {
+				fmt.Fprintln(writer, row+sep+strings.Repeat(inferMissing+sep, width-1)+inferMissing)
+			}
+			return err
+		}
+		for _, belief := range beliefList {
+			beliefs = append(beliefs, strconv.FormatFloat(belief, 'g', 6, 64))
+		}
+	}
+	fmt.Fprintln(writer, row+sep+strings.Join(beliefs, sep))
+	return nil
+}
+
+// parseDelim returns the delimiter named by flag, or the default for the extension of path.
+func parseDelim(flag string, path string) (rune, error) {
+	switch flag {
+	case "":
+		if strings.ToLower(filepath.Ext(path)) == ".csv" {
+			return ',', nil
+		}
+		return '\t', nil
+	case "comma", ",":
+		return ',', nil
+	case "tab", "\t", "\\t":
+		return '\t', nil
+	default:
+		return 0, fmt.Errorf("In function parseDelim: unsupported delimiter %s", flag)
+	}
+}
+
+// caseFileNodes returns the Nodes of net named in the header of the case file at path.
+// IDnum, NumCases and columns not naming a node are skipped.
+func caseFileNodes(net *gonetica.Network, path string, delim rune) ([]*gonetica.Node, error) {
+	var nodes []*gonetica.Node
+	// Open case file and read header and check for errors
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	// Lookup node for each column
+	for _, name := range strings.Split(strings.TrimRight(header, "\r\n"), string(delim)) {
+		name = strings.TrimSpace(name)
+		if name == "IDnum" || name == "NumCases" || name == "" {
+			continue
+		}
+		node, err := net.NodeNamed(name)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
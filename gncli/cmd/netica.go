@@ -0,0 +1,58 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/slee21/gonetica"
+	"github.com/slee21/gonetica/native"
+)
+
+// defaultBackend is the backend serving Bayesnets unless selected with --backend.
+const defaultBackend = "netica"
+
+var neticaEnv *gonetica.Environment
+
+// initNetica initialises netica with license and checks for errors.
+func initNetica(license string) error {
+	// Initialise netica and check for errors
+	env, err := gonetica.NewEnvironment(license)
+	if err != nil {
+		return err
+	}
+	neticaEnv = env
+	return nil
+}
+
+// initBackend initialises the backend with name for serving Bayesnets, Netica with license or native.
+func initBackend(name string, license string) error {
+	switch name {
+	case "netica":
+		// Initialise netica and check for errors
+		if err := initNetica(license); err != nil {
+			return err
+		}
+		netBackend = gonetica.NewNeticaBackend(neticaEnv)
+	case "native":
+		netBackend = native.NewBackend()
+	default:
+		return fmt.Errorf("In function initBackend: unknown backend %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo || nonetica
+// +build !cgo nonetica
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"github.com/slee21/gonetica/native"
+)
+
+// defaultBackend is the backend serving Bayesnets unless selected with --backend.
+const defaultBackend = "native"
+
+// initBackend initialises the native backend for serving Bayesnets, as gncli was built without Netica.
+func initBackend(name string, license string) error {
+	if name != "native" {
+		return fmt.Errorf("In function initBackend: backend %s not available as gncli was built without Netica", name)
+	}
+	netBackend = native.NewBackend()
+	return nil
+}
+
+// analysisRoutes returns no routes as analysis requires Netica.
+func analysisRoutes() ([]*rest.Route, []map[string]string) {
+	return nil, nil
+}
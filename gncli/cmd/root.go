@@ -31,7 +31,7 @@ var (
 	cfgFile string
 	exeDir  string
 
-	neticaEnv *gonetica.Environment
+	netBackend gonetica.Backend
 )
 
 // RootCmd represents the base command when called without any subcommands.
@@ -99,14 +99,3 @@ func initExeDir() error {
 	exeDir = dir
 	return nil
 }
-
-// initNetica initialises netica with license and checks for errors.
-func initNetica(license string) error {
-	// Initialise netica and check for errors
-	env, err := gonetica.NewEnvironment(license)
-	if err != nil {
-		return err
-	}
-	neticaEnv = env
-	return nil
-}
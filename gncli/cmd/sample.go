@@ -0,0 +1,182 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/slee21/gonetica"
+)
+
+var (
+	sampleNet      string
+	sampleN        int
+	sampleSeed     int64
+	sampleOut      string
+	sampleNodes    []string
+	sampleMethod   string
+	sampleFindings []string
+)
+
+// sampleMethods maps sampling method flag values to Netica sampling algorithms.
+var sampleMethods = map[string]gonetica.SamplingMethod{
+	"default":  gonetica.DefaultSampling,
+	"jointree": gonetica.JoinTreeSampling,
+	"forward":  gonetica.ForwardSampling,
+}
+
+// sampleCmd represents the sample command
+var sampleCmd = &cobra.Command{
+	Use:   "sample",
+	Short: "Generate random cases from a Bayesnet",
+	Long: `Sample generates random cases from the joint distribution of a Bayesnet,
+optionally conditioned on findings, and writes them as a comma delimited
+case file with a header row of node names. A seed makes the output
+reproducible.`,
+	RunE: runSample,
+}
+
+func init() {
+	RootCmd.AddCommand(sampleCmd)
+
+	// Initialise flags
+	sampleCmd.Flags().StringVar(&sampleNet, "net", "", "Netica Bayesnet file to sample from")
+	sampleCmd.Flags().IntVar(&sampleN, "n", 1000, "number of cases to generate")
+	sampleCmd.Flags().Int64Var(&sampleSeed, "seed", 0, "random seed (default from the clock)")
+	sampleCmd.Flags().StringVar(&sampleOut, "out", "", "case file to write (default stdout)")
+	sampleCmd.Flags().StringSliceVar(&sampleNodes, "nodes", nil, "comma separated names of nodes to sample (default all nodes without findings)")
+	sampleCmd.Flags().StringVar(&sampleMethod, "method", "default", "sampling method, default, jointree or forward")
+	sampleCmd.Flags().StringSliceVar(&sampleFindings, "finding", nil, "finding to condition on as node=evidence, may be repeated")
+}
+
+// runSample generates random cases from a Bayesnet and writes them as a case file.
+func runSample(cmd *cobra.Command, args []string) error {
+	// Check flags
+	if sampleNet == "" {
+		return fmt.Errorf("In function sample: --net is required")
+	}
+	method, ok := sampleMethods[sampleMethod]
+	if !ok {
+		return fmt.Errorf("In function sample: unsupported method %s", sampleMethod)
+	}
+	evidence, err := parseFindings(sampleFindings)
+	if err != nil {
+		return err
+	}
+	// Initialise Netica and check for errors
+	err = initNetica(viper.GetString("license"))
+	if err != nil {
+		return err
+	}
+	defer neticaEnv.CloseEnvironment()
+	// Read Bayesnet and check for errors
+	net, err := gonetica.NewNetwork(neticaEnv, sampleNet)
+	if err != nil {
+		return err
+	}
+	defer net.CloseNetwork()
+	// Seed from the clock unless a seed is given
+	seed := sampleSeed
+	if !cmd.Flags().Changed("seed") {
+		seed = time.Now().UnixNano()
+	}
+	// Lookup nodes to sample and check for errors
+	var nodes []*gonetica.Node
+	for _, name := range sampleNodes {
+		node, err := net.NodeNamed(name)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, node)
+	}
+	// Enter findings to condition on and check for errors
+	err = net.EnterCase(evidence)
+	if err != nil {
+		return err
+	}
+	// Generate cases and check for errors
+	cases, err := net.Sample(sampleN, nodes, method, seed)
+	if err != nil {
+		return err
+	}
+	// Open output file and check for errors
+	var out io.Writer = os.Stdout
+	if sampleOut != "" {
+		file, err := os.Create(sampleOut)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	return writeCases(out, cases)
+}
+
+// parseFindings parses findings given as node=evidence into a case.
+func parseFindings(findings []string) (map[string]string, error) {
+	evidence := make(map[string]string)
+	for _, finding := range findings {
+		parts := strings.SplitN(finding, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("In function parseFindings: invalid finding %s, expected node=evidence", finding)
+		}
+		evidence[parts[0]] = parts[1]
+	}
+	return evidence, nil
+}
+
+// writeCases writes cases as a comma delimited case file with a header row of node names sorted lexicographically.
+func writeCases(w io.Writer, cases []map[string]string) error {
+	var names []string
+	// Collect names of all nodes in any case
+	seen := make(map[string]bool)
+	for _, caseMap := range cases {
+		for name := range caseMap {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	// Write header row then a row per case, with * for missing values
+	writer := csv.NewWriter(w)
+	writer.Write(names)
+	for _, caseMap := range cases {
+		row := make([]string, len(names))
+		for index, name := range names {
+			if value, ok := caseMap[name]; ok && value != "" {
+				row[index] = value
+			} else {
+				row[index] = "*"
+			}
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -19,6 +19,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,11 +28,13 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/slee21/gonetica"
+	"github.com/slee21/gonetica/convert"
 )
 
 var (
-	netList   []*gonetica.Network
-	netLookup map[string]*gonetica.Network
+	netList   []gonetica.BackendNetwork
+	netLookup map[string]gonetica.BackendNetwork
+	netPaths  map[string]gonetica.BackendNetwork
 
 	serveLock sync.RWMutex
 
@@ -39,13 +42,25 @@ var (
 	apiRoutes []map[string]string
 )
 
+// netConfig holds per-network load options read from the nets section of the config file.
+type netConfig struct {
+	Path            string               `mapstructure:"path"`
+	TimeSlices      int                  `mapstructure:"time-slices"`
+	Levels          map[string][]float64 `mapstructure:"levels"`
+	Bins            int                  `mapstructure:"bins"`
+	Low             float64              `mapstructure:"low"`
+	High            float64              `mapstructure:"high"`
+	EquationSamples int                  `mapstructure:"equation-samples"`
+}
+
 // serveCmd represents the server command
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Serve HTTP requests for Bayesian inference with Netica",
-	Long: `Serve starts a long-running server process that loads Bayesnets once on startup
+	Long: `Serve starts a long-running server process that loads Bayesnets on startup
 then performs Bayesian inference in response to HTTP requests indicating the
-target Bayesnet and case data. It does not support HTTPS and should be 
+target Bayesnet and case data. Bayesnets are reloaded as files in the directory
+change unless watching is disabled. It does not support HTTPS and should be 
 proxied behind a real webserver such as Apache or Nginx if desired.
 Serves JSON by default.`,
 	RunE: serveJSON,
@@ -65,12 +80,20 @@ func init() {
 	serveCmd.PersistentFlags().String("bind", "127.0.0.1", "interface to which the server will bind")
 	serveCmd.PersistentFlags().Int("port", 8080, "port on which the server will listen")
 	serveCmd.PersistentFlags().String("prefix", "api", "path prefix from which requests will be served")
+	serveCmd.PersistentFlags().Bool("watch", true, "reload Bayesnets when files in dir change")
+	serveCmd.PersistentFlags().String("admin-token", "", "bearer token required to upload, replace and delete Bayesnets (default admin routes disabled)")
+	serveCmd.PersistentFlags().String("backend", defaultBackend, "inference backend, netica or native (pure Go, .dne files only)")
+	serveCmd.PersistentFlags().Int64("max-body", 32<<20, "maximum size in bytes of request bodies")
 
 	// Bind flags to 12 factor interface
 	viper.BindPFlag("dir", serveCmd.PersistentFlags().Lookup("dir"))
 	viper.BindPFlag("port", serveCmd.PersistentFlags().Lookup("port"))
 	viper.BindPFlag("bind", serveCmd.PersistentFlags().Lookup("bind"))
 	viper.BindPFlag("prefix", serveCmd.PersistentFlags().Lookup("prefix"))
+	viper.BindPFlag("watch", serveCmd.PersistentFlags().Lookup("watch"))
+	viper.BindPFlag("admin-token", serveCmd.PersistentFlags().Lookup("admin-token"))
+	viper.BindPFlag("backend", serveCmd.PersistentFlags().Lookup("backend"))
+	viper.BindPFlag("max-body", serveCmd.PersistentFlags().Lookup("max-body"))
 
 	// Add subcommands based on request format
 	serveCmd.AddCommand(serveJSONCmd)
@@ -89,16 +112,21 @@ func initAPIPrefix(prefix string) string {
 	return path
 }
 
-// initServe initialises Netica and reads available Bayesnets before server start.
+// initServe initialises the inference backend and reads available Bayesnets before server start.
 func initServe() error {
-	// Initialise Netica and check for errors
-	err := initNetica(viper.GetString("license"))
+	// Initialise backend and check for errors
+	err := initBackend(viper.GetString("backend"), viper.GetString("license"))
 	if err != nil {
 		return err
 	}
+	// Convert Bayesnets in other file formats before loading
+	netBackend = convert.NewBackend(netBackend)
 	// Read Bayesnets in dir, index them by relative path and check for errors
 	serveLock.Lock()
-	netList, netLookup, err = indexNets(neticaEnv, viper.GetString("dir"))
+	netPaths, err = indexNets(netBackend, viper.GetString("dir"))
+	if err == nil {
+		netList, netLookup = listNets(netPaths)
+	}
 	serveLock.Unlock()
 	if err != nil {
 		return err
@@ -108,41 +136,89 @@ func initServe() error {
 	return nil
 }
 
-// indexNets reads Netica Bayesnets in dir into env and index them in a list and map.
-func indexNets(env *gonetica.Environment, dir string) ([]*gonetica.Network, map[string]*gonetica.Network, error) {
-	var nets []*gonetica.Network
-	var lookup = make(map[string]*gonetica.Network)
+// indexNets reads Bayesnets in dir with backend and index them by relative path.
+func indexNets(backend gonetica.Backend, dir string) (map[string]gonetica.BackendNetwork, error) {
+	var paths = make(map[string]gonetica.BackendNetwork)
+	var names = make(map[string]string)
 	root := filepath.Clean(dir)
 	// Recursively iterate over files in dir and check for errors
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		// Only process .dne and .neta files
-		if !info.IsDir() && (filepath.Ext(path) == ".dne" || filepath.Ext(path) == ".neta") {
-			// Read file into Netica Bayesnet and check for errors
-			net, err := gonetica.NewNetwork(neticaEnv, path)
+		// Only process files in supported Bayesnet formats
+		if !info.IsDir() && isNetFile(path) {
+			// Get relative path of path from root
+			relPath, _ := filepath.Rel(root, path)
+			// Read file into Bayesnet and check for errors
+			net, err := gonetica.LoadFile(backend, path, netOptions(relPath))
 			if err != nil {
 				// If error reading net, log error and skip
 				log.Println(err)
 				return nil
 			}
 			name := net.Name()
-			// Get relative path of path from root
-			relPath, _ := filepath.Rel(root, path)
 			// Check if network with name already exists
-			if lookup[name] != nil {
+			if _, ok := names[name]; ok {
 				net.CloseNetwork()
-				err = fmt.Errorf("In function serve: network named %s already loaded from path %s", name, relPath)
+				err = fmt.Errorf("In function serve: network named %s already loaded from path %s", name, names[name])
 				log.Println(err)
 				return nil
 			}
-			// Index network in lists and map
-			nets = append(nets, net)
-			lookup[name] = net
-			lookup[strconv.Itoa(len(nets)-1)] = net
+			// Index network by path
+			names[name] = relPath
+			paths[relPath] = net
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	return paths, nil
+}
+
+// listNets indexes Networks in a list ordered by relative path and a map by name and list index.
+func listNets(paths map[string]gonetica.BackendNetwork) ([]gonetica.BackendNetwork, map[string]gonetica.BackendNetwork) {
+	var nets []gonetica.BackendNetwork
+	var lookup = make(map[string]gonetica.BackendNetwork)
+	var relPaths []string
+	for relPath := range paths {
+		relPaths = append(relPaths, relPath)
+	}
+	// Sort paths to match directory walk order
+	sort.Strings(relPaths)
+	// Index network in lists and map
+	for _, relPath := range relPaths {
+		net := paths[relPath]
+		nets = append(nets, net)
+		lookup[net.Name()] = net
+		lookup[strconv.Itoa(len(nets)-1)] = net
+	}
+	return nets, lookup
+}
+
+// isNetFile returns whether path has a supported Bayesnet file extension, .neta or a format of package convert.
+func isNetFile(path string) bool {
+	return filepath.Ext(path) == ".neta" || convert.ForPath(path) != nil
+}
+
+// netOptions returns the load options configured for the Bayesnet at relPath in the nets section of the config file.
+func netOptions(relPath string) gonetica.LoadOptions {
+	var configs []netConfig
+	var options gonetica.LoadOptions
+	// Read per-network config and log errors
+	if err := viper.UnmarshalKey("nets", &configs); err != nil {
+		log.Println(err)
+		return options
+	}
+	for _, config := range configs {
+		if filepath.Clean(config.Path) == filepath.Clean(relPath) {
+			options = gonetica.LoadOptions{
+				TimeSlices:      config.TimeSlices,
+				Levels:          config.Levels,
+				Bins:            config.Bins,
+				Low:             config.Low,
+				High:            config.High,
+				EquationSamples: config.EquationSamples,
+			}
+		}
 	}
-	return nets, lookup, nil
+	return options
 }
@@ -15,15 +15,20 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/slee21/gonetica"
 )
 
 // netJSON is the JSON representation of a Network.
@@ -36,19 +41,28 @@ type netJSON struct {
 }
 
 // nodeJSON is the JSON respresentation of a Node.
+// The conditional probability table is only populated when requested with include=cpt.
 type nodeJSON struct {
-	Index   int       `json:"index"`
-	Name    string    `json:"name"`
-	Title   string    `json:"title"`
-	Comment string    `json:"comment"`
-	States  []string  `json:"states"`
-	Levels  []float64 `json:"levels"`
+	Index    int         `json:"index"`
+	Name     string      `json:"name"`
+	Title    string      `json:"title"`
+	Comment  string      `json:"comment"`
+	Kind     string      `json:"kind"`
+	Discrete bool        `json:"discrete"`
+	States   []string    `json:"states"`
+	Levels   []float64   `json:"levels"`
+	Parents  []string    `json:"parents"`
+	Children []string    `json:"children"`
+	CPT      [][]float64 `json:"cpt,omitempty"`
 }
 
 // caseJSON is the JSON respresentation of a Case for Bayesian inference.
 type caseJSON struct {
-	ID    string              `json:"id"`
-	Cases []map[string]string `json:"cases"`
+	ID        string              `json:"id"`
+	Posterior bool                `json:"posterior"`
+	Targets   []string            `json:"targets"`
+	Query     map[string]string   `json:"query"`
+	Cases     []map[string]string `json:"cases"`
 }
 
 // batchJSON is the JSON respresentation of the batch results of Bayesian inference.
@@ -58,10 +72,28 @@ type batchJSON struct {
 }
 
 // singleJSON is the JSON respresentation of a single result of Bayesian inference.
+// The posterior fields are only populated when requested in the caseJSON payload.
 type singleJSON struct {
-	Index int    `json:"index"`
-	Error string `json:"error"`
-	Value string `json:"value"`
+	Index        int       `json:"index"`
+	Error        string    `json:"error"`
+	Value        string    `json:"value"`
+	Beliefs      []float64 `json:"beliefs,omitempty"`
+	Expected     *float64  `json:"expected_value,omitempty"`
+	StdDev       *float64  `json:"std_dev,omitempty"`
+	FindingsProb *float64  `json:"findings_probability,omitempty"`
+}
+
+// multiBatchJSON is the JSON respresentation of the batch results of Bayesian inference on multiple nodes.
+type multiBatchJSON struct {
+	ID      string       `json:"id"`
+	Results []*multiJSON `json:"results"`
+}
+
+// multiJSON is the JSON respresentation of a single case result of Bayesian inference on multiple nodes.
+type multiJSON struct {
+	Index   int                    `json:"index"`
+	Error   string                 `json:"error"`
+	Targets map[string]*singleJSON `json:"targets"`
 }
 
 var (
@@ -95,6 +127,13 @@ func serveJSON(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	// Reload Bayesnets as files in dir change if enabled and check for errors
+	if viper.GetBool("watch") {
+		err = watchNets(viper.GetString("dir"), reloadJSON)
+		if err != nil {
+			return err
+		}
+	}
 	// Start JSON api using go-json-rest framework and check for errors
 	host := net.JoinHostPort(viper.GetString("bind"), strconv.Itoa(viper.GetInt("port")))
 	api := initMiddleware(rest.NewApi())
@@ -112,7 +151,7 @@ func buildJSON() ([]*netJSON, map[string]*netJSON, error) {
 	var nets = make(map[string]*netJSON)
 	serveLock.RLock()
 	defer serveLock.RUnlock()
-	// Iterate over Networks in neticaEnv, building JSON representation and check for errors
+	// Iterate over loaded Networks, building JSON representation and check for errors
 	for netIndex, net := range netList {
 		netRepr := &netJSON{netIndex, net.Name(), net.Title(), net.Comment(), nil}
 		nodeList, err := net.NodeList()
@@ -124,7 +163,9 @@ func buildJSON() ([]*netJSON, map[string]*netJSON, error) {
 		nodes = nil
 		// Iterate over Nodes in net, building JSON representationo and check for errors
 		for index, node := range nodeList {
-			repr := &nodeJSON{index, node.Name(), node.Title(), node.Comment(), nil, nil}
+			repr := &nodeJSON{Index: index, Name: node.Name(), Title: node.Title(), Comment: node.Comment()}
+			repr.Kind = node.Kind().String()
+			repr.Discrete = node.IsDiscreteType()
 			names, err := node.StateNameList()
 			// Check for errors, break out of Node loop on error
 			if err != nil {
@@ -137,6 +178,18 @@ func buildJSON() ([]*netJSON, map[string]*netJSON, error) {
 				break
 			}
 			repr.Levels = levels
+			parents, err := node.ParentList()
+			// Check for errors, break out of Node loop on error
+			if err != nil {
+				break
+			}
+			repr.Parents = nodeNames(parents)
+			children, err := node.ChildList()
+			// Check for errors, break out of Node loop on error
+			if err != nil {
+				break
+			}
+			repr.Children = nodeNames(children)
 			nodes = append(nodes, repr)
 		}
 		// If error building net JSON representation, log error and skip
@@ -153,18 +206,50 @@ func buildJSON() ([]*netJSON, map[string]*netJSON, error) {
 	return list, nets, nil
 }
 
+// reloadJSON applies swap to loaded Networks and rebuilds their JSON representation atomically.
+func reloadJSON(swap func()) {
+	serveJSONLock.Lock()
+	defer serveJSONLock.Unlock()
+	swap()
+	// Build structs for JSON outputs and check for errors
+	list, nets, err := buildJSON()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	netJSONList, netsJSON = list, nets
+}
+
 // initMiddleware initialises Middleware to add functionality to the JSON API.
 func initMiddleware(api *rest.Api) *rest.Api {
-	api.Use(rest.DefaultProdStack...)
+	// Use default stack except for content type checking
+	stack := rest.DefaultProdStack[:len(rest.DefaultProdStack)-1]
+	api.Use(stack...)
+	// Limit size of request bodies
+	api.Use(rest.MiddlewareSimple(func(handler rest.HandlerFunc) rest.HandlerFunc {
+		return func(w rest.ResponseWriter, r *rest.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w.(http.ResponseWriter), r.Body, viper.GetInt64("max-body"))
+			}
+			handler(w, r)
+		}
+	}))
+	// Only check for JSON content type if request is not a Bayesnet upload
+	api.Use(&rest.IfMiddleware{
+		Condition: func(request *rest.Request) bool {
+			return !isUpload(request)
+		},
+		IfTrue: &rest.ContentTypeCheckerMiddleware{},
+	})
 	// allow cross-origin resource sharing
 	api.Use(&rest.CorsMiddleware{
 		RejectNonCorsRequests: false,
 		OriginValidator: func(origin string, request *rest.Request) bool {
 			return true
 		},
-		AllowedMethods: []string{"GET", "POST", "PUT"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
 		AllowedHeaders: []string{
-			"Accept", "Content-Type", "X-Custom-Header", "Origin"},
+			"Accept", "Authorization", "Content-Type", "X-Custom-Header", "Origin"},
 		AccessControlAllowCredentials: true,
 		AccessControlMaxAge:           3600,
 	})
@@ -173,15 +258,29 @@ func initMiddleware(api *rest.Api) *rest.Api {
 
 // initRouter initialises the JSON API request router.
 func initRouter(api *rest.Api, prefix string) (*rest.Api, error) {
-	// Initialise router and check for errors
-	router, err := rest.MakeRouter(
+	routes := []*rest.Route{
 		rest.Get(apiPrefix, getAPI),
 		rest.Get(apiPrefix+"/nets", getNets),
 		rest.Get(apiPrefix+"/nets/#netid", getNet),
 		rest.Get(apiPrefix+"/nets/#netid/nodes", getNetNodes),
 		rest.Get(apiPrefix+"/nets/#netid/nodes/#nodeid", getNetNode),
 		rest.Post(apiPrefix+"/nets/#netid/nodes/#nodeid", postNetNode),
-	)
+		rest.Post(apiPrefix+"/nets/#netid/infer", postNetInfer),
+		rest.Get(apiPrefix+"/nets/#netid/graph", getNetGraph),
+	}
+	// Add analysis routes supported by backend
+	analysis, analysisDocs := analysisRoutes()
+	routes = append(routes, analysis...)
+	// Add admin routes only if protected by admin token
+	if viper.GetString("admin-token") != "" {
+		routes = append(routes,
+			rest.Post(apiPrefix+"/nets", adminOnly(postNets)),
+			rest.Put(apiPrefix+"/nets/#netid", adminOnly(putNet)),
+			rest.Delete(apiPrefix+"/nets/#netid", adminOnly(deleteNet)),
+		)
+	}
+	// Initialise router and check for errors
+	router, err := rest.MakeRouter(routes...)
 	api.SetApp(router)
 	if err != nil {
 		return nil, err
@@ -198,10 +297,30 @@ func initRouter(api *rest.Api, prefix string) (*rest.Api, error) {
 			"description": "List all nodes contained in #netid."},
 		{"path": apiPrefix + "/nets/#netid/nodes/#nodeid",
 			"method":      "GET",
-			"description": "Describe #nodeid in #netid."},
+			"description": "Describe #nodeid in #netid, ?include=cpt adds its conditional probability table."},
 		{"path": apiPrefix + "/nets/#netid/nodes/#nodeid",
 			"method":      "POST",
-			"description": "Perform Bayesian inference on #netid with #nodeid as target node and JSON payload as cases."},
+			"description": "Perform Bayesian inference on #netid with #nodeid as target node and JSON payload as cases, set posterior to return beliefs."},
+		{"path": apiPrefix + "/nets/#netid/infer",
+			"method":      "POST",
+			"description": "Perform Bayesian inference on #netid with JSON payload as cases and targets as target nodes, * for all unobserved nodes."},
+		{"path": apiPrefix + "/nets/#netid/graph",
+			"method":      "GET",
+			"description": "Draw #netid as ?format=svg|dot, ?beliefs=true lists state beliefs given ?finding=node=evidence, nodes with findings highlighted."},
+	}
+	apiRoutes = append(apiRoutes, analysisDocs...)
+	if viper.GetString("admin-token") != "" {
+		apiRoutes = append(apiRoutes,
+			map[string]string{"path": apiPrefix + "/nets",
+				"method":      "POST",
+				"description": "Load Bayesian network from request body, ?format=dne|neta|xmlbif|bif|hugin|xdsl|uai. Requires admin token."},
+			map[string]string{"path": apiPrefix + "/nets/#netid",
+				"method":      "PUT",
+				"description": "Replace #netid with Bayesian network from request body, ?format=dne|neta|xmlbif|bif|hugin|xdsl|uai. Requires admin token."},
+			map[string]string{"path": apiPrefix + "/nets/#netid",
+				"method":      "DELETE",
+				"description": "Unload #netid and remove its file. Requires admin token."},
+		)
 	}
 	return api, nil
 }
@@ -213,11 +332,15 @@ func getAPI(w rest.ResponseWriter, r *rest.Request) {
 
 // getNets returns JSON listing all loaded Networks.
 func getNets(w rest.ResponseWriter, r *rest.Request) {
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
 	w.WriteJson(netJSONList)
 }
 
 // getNet returns JSON detailing specific Network and contained nodes.
 func getNet(w rest.ResponseWriter, r *rest.Request) {
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
 	netID := r.PathParam("netid")
 	// Return Network JSON representation if loaded, NotFound otherwise
 	if repr, ok := netsJSON[netID]; ok {
@@ -229,6 +352,8 @@ func getNet(w rest.ResponseWriter, r *rest.Request) {
 
 // getNetNodes returns JSON nodes contained in a specific Network.
 func getNetNodes(w rest.ResponseWriter, r *rest.Request) {
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
 	netID := r.PathParam("netid")
 	// Return Network JSON representation if loaded, NotFound otherwise
 	if repr, ok := netsJSON[netID]; ok {
@@ -240,12 +365,23 @@ func getNetNodes(w rest.ResponseWriter, r *rest.Request) {
 
 // getNetNode returns JSON a specific node contained in a specific Network.
 func getNetNode(w rest.ResponseWriter, r *rest.Request) {
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
 	netID := r.PathParam("netid")
 	// Return Network JSON representation if loaded, NotFound otherwise
 	if repr, ok := netsJSON[netID]; ok {
 		nodeID := r.PathParam("nodeid")
 		for index, node := range repr.Nodes {
 			if strconv.Itoa(index) == nodeID || node.Name == nodeID {
+				// Add conditional probability table if requested and check for errors
+				if hasInclude(r, "cpt") {
+					withCPT, status, err := buildCPT(netID, node)
+					if err != nil {
+						rest.Error(w, err.Error(), status)
+						return
+					}
+					node = withCPT
+				}
 				w.WriteJson(node)
 				return
 			}
@@ -258,25 +394,20 @@ func getNetNode(w rest.ResponseWriter, r *rest.Request) {
 
 // postNetNode returns JSON Bayesian inference results of a specific node in a specific network given JSON payload case.
 func postNetNode(w rest.ResponseWriter, r *rest.Request) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
 	netID := r.PathParam("netid")
 	// Validated target network and node and check for errors
 	if repr, ok := netsJSON[netID]; ok {
 		net := netLookup[netID]
-		// Attempt to lookup node by name
-		nodeID := r.PathParam("nodeid")
-		node, err := net.NodeNamed(nodeID)
+		// Lookup node by name or index and check for errors
+		node, err := lookupNode(net, repr, r.PathParam("nodeid"))
 		if err != nil {
-			// Attempt to lookup node by index
-			index, err := strconv.Atoi(nodeID)
-			if err != nil {
-				rest.NotFound(w, r)
-				return
-			}
-			node, err = net.NodeNamed(repr.Nodes[index].Name)
-			if err != nil {
-				rest.NotFound(w, r)
-				return
-			}
+			rest.NotFound(w, r)
+			return
 		}
 		// Decode case data from JSON payload and check for errors
 		infer := new(caseJSON)
@@ -294,25 +425,206 @@ func postNetNode(w rest.ResponseWriter, r *rest.Request) {
 			if err != nil {
 				net.Unlock()
 				log.Println(err)
-				batch.Results = append(batch.Results, &singleJSON{index, err.Error(), ""})
-				continue
-			}
-			// Infer value of target node and check for errors
-			result, err := node.Infer()
-			if err != nil {
-				net.ClearCases()
-				net.Unlock()
-				log.Println(err)
-				batch.Results = append(batch.Results, &singleJSON{index, err.Error(), ""})
+				batch.Results = append(batch.Results, &singleJSON{Index: index, Error: err.Error()})
 				continue
 			}
+			// Infer value of target node
+			single := inferNode(index, net, node, infer.Posterior)
 			// Clear cases from network and append result to batch
 			net.ClearCases()
 			net.Unlock()
-			batch.Results = append(batch.Results, &singleJSON{index, "", result})
+			batch.Results = append(batch.Results, single)
 		}
 		w.WriteJson(batch)
 	} else {
 		rest.NotFound(w, r)
 	}
 }
+
+// postNetInfer returns JSON Bayesian inference results of multiple nodes in a specific network given JSON payload case.
+func postNetInfer(w rest.ResponseWriter, r *rest.Request) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	netID := r.PathParam("netid")
+	// Validated target network and check for errors
+	repr, ok := netsJSON[netID]
+	if !ok {
+		rest.NotFound(w, r)
+		return
+	}
+	net := netLookup[netID]
+	// Decode case data from JSON payload and check for errors
+	infer := new(caseJSON)
+	err := r.DecodeJsonPayload(infer)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Lookup target nodes by name or index, * targets all unobserved nodes
+	var targets []gonetica.BackendNode
+	var all bool
+	for _, nodeID := range infer.Targets {
+		if nodeID == "*" {
+			all = true
+			continue
+		}
+		node, err := lookupNode(net, repr, nodeID)
+		if err != nil {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		targets = append(targets, node)
+	}
+	if all {
+		targets, err = net.NodeList()
+		if err != nil {
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if len(targets) == 0 {
+		rest.Error(w, "In function postNetInfer: no target nodes", http.StatusBadRequest)
+		return
+	}
+	batch := &multiBatchJSON{infer.ID, nil}
+	// Iterate over case data and build up results and check for errors
+	for index, evidence := range infer.Cases {
+		multi := &multiJSON{Index: index, Targets: make(map[string]*singleJSON)}
+		// Enter case data once for all targets and check for errors
+		net.Lock()
+		err = net.EnterCase(evidence)
+		if err != nil {
+			net.Unlock()
+			log.Println(err)
+			multi.Error = err.Error()
+			batch.Results = append(batch.Results, multi)
+			continue
+		}
+		// Infer posterior of each target node, skipping observed nodes if targeting all
+		for _, node := range targets {
+			name := node.Name()
+			if _, observed := evidence[name]; all && observed {
+				continue
+			}
+			multi.Targets[name] = inferNode(index, net, node, true)
+		}
+		// Clear cases from network and append result to batch
+		net.ClearCases()
+		net.Unlock()
+		batch.Results = append(batch.Results, multi)
+	}
+	w.WriteJson(batch)
+}
+
+// buildCPT returns a copy of repr with the conditional probability table of the node in network netID.
+// Rows with undefined probabilities are null.
+func buildCPT(netID string, repr *nodeJSON) (*nodeJSON, int, error) {
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	// Lookup network and node and check for errors
+	net, ok := netLookup[netID]
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("In function buildCPT: network %s not loaded", netID)
+	}
+	node, err := net.NodeNamed(repr.Name)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	// Read table and check for errors
+	net.Lock()
+	table, err := node.CPT()
+	net.Unlock()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	// Replace rows with undefined probabilities, which cannot be encoded in JSON
+	for index, row := range table {
+		for _, prob := range row {
+			if math.IsNaN(prob) {
+				table[index] = nil
+				break
+			}
+		}
+	}
+	withCPT := *repr
+	withCPT.CPT = table
+	return &withCPT, http.StatusOK, nil
+}
+
+// lookupNode returns Node in net identified by name or by index in repr.
+func lookupNode(net gonetica.BackendNetwork, repr *netJSON, nodeID string) (gonetica.BackendNode, error) {
+	// Attempt to lookup node by name
+	node, err := net.NodeNamed(nodeID)
+	if err == nil {
+		return node, nil
+	}
+	// Attempt to lookup node by index
+	index, convErr := strconv.Atoi(nodeID)
+	if convErr != nil || index < 0 || index >= len(repr.Nodes) {
+		return nil, err
+	}
+	return net.NodeNamed(repr.Nodes[index].Name)
+}
+
+// inferNode returns JSON Bayesian inference result of node given findings entered in net.
+func inferNode(index int, net gonetica.BackendNetwork, node gonetica.BackendNode, posterior bool) *singleJSON {
+	// Infer value of target node and check for errors
+	result, err := node.Infer()
+	if err != nil {
+		log.Println(err)
+		return &singleJSON{Index: index, Error: err.Error()}
+	}
+	single := &singleJSON{Index: index, Value: result}
+	// Add posterior distribution if requested and check for errors
+	if posterior {
+		if err := buildPosterior(single, net, node); err != nil {
+			log.Println(err)
+			single.Error = err.Error()
+		}
+	}
+	return single
+}
+
+// hasInclude returns whether the include query parameter of r lists field.
+func hasInclude(r *rest.Request, field string) bool {
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(include) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeNames returns the names of nodes in order.
+func nodeNames(nodes []gonetica.BackendNode) []string {
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name())
+	}
+	return names
+}
+
+// buildPosterior adds the posterior distribution of node given entered findings in net to result.
+func buildPosterior(result *singleJSON, net gonetica.BackendNetwork, node gonetica.BackendNode) error {
+	// Get beliefs of each state and check for errors
+	beliefs, err := node.BeliefList()
+	if err != nil {
+		return err
+	}
+	result.Beliefs = beliefs
+	// Get expected value if node has levels
+	if value, stdDev, err := node.Value(); err == nil {
+		result.Expected = &value
+		result.StdDev = &stdDev
+	}
+	// Get probability of findings and check for errors
+	prob, err := net.FindingsProbability()
+	if err != nil {
+		return err
+	}
+	result.FindingsProb = &prob
+	return nil
+}
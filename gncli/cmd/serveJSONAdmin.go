@@ -0,0 +1,288 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/spf13/viper"
+
+	"github.com/slee21/gonetica"
+	"github.com/slee21/gonetica/convert"
+)
+
+// adminOnly wraps handler to require the admin token as bearer authorization.
+func adminOnly(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		expected := []byte("Bearer " + viper.GetString("admin-token"))
+		actual := []byte(r.Header.Get("Authorization"))
+		// Reject requests without matching token
+		if viper.GetString("admin-token") == "" || subtle.ConstantTimeCompare(expected, actual) != 1 {
+			rest.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// isUpload returns whether request carries a Bayesnet file rather than JSON.
+func isUpload(request *rest.Request) bool {
+	return request.Method == "PUT" || (request.Method == "POST" && request.URL.Path == apiPrefix+"/nets")
+}
+
+// postNets loads a Bayesnet from the request body and returns its JSON representation.
+// The file is saved in dir under the network name so it persists across restarts.
+func postNets(w rest.ResponseWriter, r *rest.Request) {
+	// Read Bayesnet file format and contents and check for errors
+	ext, err := netFormat(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	buf, status, err := readBody(r)
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	var name string
+	reloadJSON(func() {
+		serveLock.Lock()
+		defer serveLock.Unlock()
+		// Read Bayesnet and check for errors
+		var net gonetica.BackendNetwork
+		net, err = netBackend.Load("upload"+ext, buf, gonetica.LoadOptions{})
+		if err != nil {
+			status = http.StatusBadRequest
+			return
+		}
+		// Check if network with name already loaded
+		name = net.Name()
+		if _, ok := netLookup[name]; ok {
+			net.CloseNetwork()
+			err = fmt.Errorf("In function postNets: network named %s already loaded", name)
+			status = http.StatusConflict
+			return
+		}
+		// Reload with the options configured for its saved path, as when loaded from dir
+		relPath := name + ext
+		if options := netOptions(relPath); hasOptions(options) {
+			net.CloseNetwork()
+			net, err = netBackend.Load(relPath, buf, options)
+			if err != nil {
+				status = http.StatusBadRequest
+				return
+			}
+		}
+		status, err = saveNet(relPath, net, buf)
+	})
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	writeNetJSON(w, r, name)
+}
+
+// putNet loads a Bayesnet from the request body replacing #netid and returns its JSON representation.
+// If #netid is not loaded, a new network is added which must be named #netid.
+// The file of a replaced network is renamed to the extension of the requested format.
+func putNet(w rest.ResponseWriter, r *rest.Request) {
+	netID := r.PathParam("netid")
+	// Read Bayesnet file format and contents and check for errors
+	ext, err := netFormat(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	buf, status, err := readBody(r)
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	var name string
+	reloadJSON(func() {
+		serveLock.Lock()
+		defer serveLock.Unlock()
+		// Replace file of loaded network in the requested format, a network not loaded must be addressed by name
+		old, loaded := netLookup[netID]
+		relPath := netID + ext
+		if loaded {
+			oldPath := netPath(old)
+			relPath = strings.TrimSuffix(oldPath, filepath.Ext(oldPath)) + ext
+		} else if _, convErr := strconv.Atoi(netID); convErr == nil {
+			err = fmt.Errorf("In function putNet: network %s not loaded", netID)
+			status = http.StatusNotFound
+			return
+		}
+		// Read Bayesnet and check for errors
+		var net gonetica.BackendNetwork
+		net, err = netBackend.Load(filepath.Base(relPath), buf, netOptions(relPath))
+		if err != nil {
+			status = http.StatusBadRequest
+			return
+		}
+		// Check network name matches #netid unless addressed by index
+		name = net.Name()
+		if _, convErr := strconv.Atoi(netID); convErr != nil && name != netID {
+			net.CloseNetwork()
+			err = fmt.Errorf("In function putNet: network named %s does not match %s", name, netID)
+			status = http.StatusBadRequest
+			return
+		}
+		if !loaded || netPath(old) == relPath {
+			status, err = saveNet(relPath, net, buf)
+			return
+		}
+		// Unindex file in previous format so the network can be indexed at its new path
+		oldPath := netPath(old)
+		delete(netPaths, oldPath)
+		status, err = saveNet(relPath, net, buf)
+		if netPaths[relPath] != net {
+			// Keep previous version if the new version could not be indexed
+			netPaths[oldPath] = old
+			netList, netLookup = listNets(netPaths)
+			return
+		}
+		// Close previous version and remove its file once the new file is written
+		old.CloseNetwork()
+		if err != nil {
+			return
+		}
+		if removeErr := os.Remove(filepath.Join(filepath.Clean(viper.GetString("dir")), oldPath)); removeErr != nil && !os.IsNotExist(removeErr) {
+			log.Println(removeErr)
+		}
+	})
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	writeNetJSON(w, r, name)
+}
+
+// deleteNet unloads #netid and removes its file from dir.
+func deleteNet(w rest.ResponseWriter, r *rest.Request) {
+	netID := r.PathParam("netid")
+	var err error
+	var status int
+	reloadJSON(func() {
+		serveLock.Lock()
+		defer serveLock.Unlock()
+		// Check network is loaded
+		net, ok := netLookup[netID]
+		if !ok {
+			err = fmt.Errorf("In function deleteNet: network %s not loaded", netID)
+			status = http.StatusNotFound
+			return
+		}
+		// Close network and remove from index
+		relPath := netPath(net)
+		net.CloseNetwork()
+		delete(netPaths, relPath)
+		netList, netLookup = listNets(netPaths)
+		// Remove file from dir and check for errors
+		err = os.Remove(filepath.Join(filepath.Clean(viper.GetString("dir")), relPath))
+		if err != nil && !os.IsNotExist(err) {
+			status = http.StatusInternalServerError
+			return
+		}
+		err = nil
+	})
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// saveNet indexes net by relPath and writes buf to its file in dir. Caller must hold serveLock.
+func saveNet(relPath string, net gonetica.BackendNetwork, buf []byte) (int, error) {
+	// Index network by path and check for errors
+	if err := indexNet(relPath, net); err != nil {
+		return http.StatusConflict, err
+	}
+	// Write file to dir and check for errors
+	path := filepath.Join(filepath.Clean(viper.GetString("dir")), relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	// Keep watcher from reloading the file just written
+	markSavedNet(path)
+	return http.StatusOK, nil
+}
+
+// readBody reads the request body, with status RequestEntityTooLarge if it exceeds max-body.
+func readBody(r *rest.Request) ([]byte, int, error) {
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if int64(len(buf)) >= viper.GetInt64("max-body") {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("In function readBody: request body exceeds %d bytes", viper.GetInt64("max-body"))
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	return buf, http.StatusOK, nil
+}
+
+// hasOptions returns whether options differ from the defaults.
+func hasOptions(options gonetica.LoadOptions) bool {
+	return options.TimeSlices != 0 || len(options.Levels) != 0 || options.Bins != 0 || options.EquationSamples != 0
+}
+
+// netPath returns the relative path net was loaded from. Caller must hold serveLock.
+func netPath(net gonetica.BackendNetwork) string {
+	for relPath, other := range netPaths {
+		if other == net {
+			return relPath
+		}
+	}
+	return net.Name() + ".dne"
+}
+
+// netFormat returns the Bayesnet file extension selected by the format query parameter.
+func netFormat(r *rest.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "dne":
+		return ".dne", nil
+	case "neta":
+		return ".neta", nil
+	default:
+		if converted := convert.Named(format); converted != nil {
+			return converted.Exts[0], nil
+		}
+		return "", fmt.Errorf("In function netFormat: unsupported format %s", format)
+	}
+}
+
+// writeNetJSON writes the JSON representation of Network with name.
+func writeNetJSON(w rest.ResponseWriter, r *rest.Request, name string) {
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	// Return Network JSON representation if loaded, NotFound otherwise
+	if repr, ok := netsJSON[name]; ok {
+		w.WriteJson(repr)
+	} else {
+		rest.NotFound(w, r)
+	}
+}
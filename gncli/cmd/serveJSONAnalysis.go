@@ -0,0 +1,543 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"github.com/slee21/gonetica"
+)
+
+// sensBatchJSON is the JSON respresentation of the batch results of sensitivity analysis.
+type sensBatchJSON struct {
+	ID      string      `json:"id"`
+	Results []*sensJSON `json:"results"`
+}
+
+// sensJSON is the JSON respresentation of a single result of sensitivity analysis,
+// ranking nodes by how much a finding at each would change beliefs of the target node.
+type sensJSON struct {
+	Index   int         `json:"index"`
+	Error   string      `json:"error"`
+	Kind    string      `json:"kind"`
+	Ranking []*rankJSON `json:"ranking"`
+}
+
+// rankJSON is the JSON respresentation of the sensitivity of the target node to a finding at a node.
+type rankJSON struct {
+	Node  string  `json:"node"`
+	Value float64 `json:"value"`
+}
+
+// configBatchJSON is the JSON respresentation of the batch results of joint queries.
+type configBatchJSON struct {
+	ID      string        `json:"id"`
+	Results []*configJSON `json:"results"`
+}
+
+// configJSON is the JSON respresentation of a single result of a joint query,
+// a configuration of node states with its probability given the case findings.
+type configJSON struct {
+	Index        int               `json:"index"`
+	Error        string            `json:"error"`
+	Config       map[string]string `json:"config,omitempty"`
+	Probability  *float64          `json:"probability,omitempty"`
+	FindingsProb *float64          `json:"findings_probability,omitempty"`
+}
+
+// decideBatchJSON is the JSON respresentation of the batch results of decision optimisation.
+type decideBatchJSON struct {
+	ID      string        `json:"id"`
+	Results []*decideJSON `json:"results"`
+}
+
+// decideJSON is the JSON respresentation of a single result of decision optimisation.
+type decideJSON struct {
+	Index           int                      `json:"index"`
+	Error           string                   `json:"error"`
+	Decisions       map[string]*decisionJSON `json:"decisions"`
+	ExpectedUtility *float64                 `json:"expected_utility,omitempty"`
+}
+
+// decisionJSON is the JSON respresentation of the optimal decision at a decision node.
+type decisionJSON struct {
+	Optimal           string    `json:"optimal"`
+	ExpectedUtilities []float64 `json:"expected_utilities"`
+}
+
+// analysisRoutes returns the routes and their descriptions for analyses of networks loaded with Netica.
+func analysisRoutes() ([]*rest.Route, []map[string]string) {
+	routes := []*rest.Route{
+		rest.Get(apiPrefix+"/nets/#netid/nodes/#nodeid/sensitivity", getNetNodeSensitivity),
+		rest.Post(apiPrefix+"/nets/#netid/nodes/#nodeid/sensitivity", postNetNodeSensitivity),
+		rest.Post(apiPrefix+"/nets/#netid/mpe", postNetMPE),
+		rest.Post(apiPrefix+"/nets/#netid/joint", postNetJoint),
+		rest.Post(apiPrefix+"/nets/#netid/decide", postNetDecide),
+	}
+	docs := []map[string]string{
+		{"path": apiPrefix + "/nets/#netid/nodes/#nodeid/sensitivity",
+			"method":      "GET",
+			"description": "Rank nodes in #netid by sensitivity of #nodeid to their findings, ?kind=entropy|variance and ?vary=node,... to select nodes."},
+		{"path": apiPrefix + "/nets/#netid/nodes/#nodeid/sensitivity",
+			"method":      "POST",
+			"description": "Rank nodes in #netid by sensitivity of #nodeid to their findings given JSON payload as cases."},
+		{"path": apiPrefix + "/nets/#netid/mpe",
+			"method":      "POST",
+			"description": "Find the most probable configuration of all nodes in #netid given JSON payload as cases, ?nth=n for the nth most probable."},
+		{"path": apiPrefix + "/nets/#netid/joint",
+			"method":      "POST",
+			"description": "Calculate the joint probability of the node states in query given JSON payload as cases."},
+		{"path": apiPrefix + "/nets/#netid/decide",
+			"method":      "POST",
+			"description": "Find the optimal choice at each decision node in #netid and the expected utility given JSON payload as cases."},
+	}
+	return routes, docs
+}
+
+// neticaNet returns the Netica Network loaded as netID for the handler named function.
+// Caller must hold serveLock.
+func neticaNet(function string, netID string) (*gonetica.Network, int, error) {
+	loaded, ok := netLookup[netID]
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("In function %s: network %s not loaded", function, netID)
+	}
+	net, ok := gonetica.NeticaNetwork(loaded)
+	if !ok {
+		return nil, http.StatusNotImplemented, fmt.Errorf("In function %s: network %s not loaded with Netica", function, netID)
+	}
+	return net, http.StatusOK, nil
+}
+
+// lookupNeticaNode returns Node in Netica network netID identified by name or by index in repr.
+// Caller must hold serveLock.
+func lookupNeticaNode(netID string, repr *netJSON, nodeID string) (*gonetica.Node, error) {
+	node, err := lookupNode(netLookup[netID], repr, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	neticaNode, _ := gonetica.NeticaNode(node)
+	return neticaNode, nil
+}
+
+// getNetNodeSensitivity returns JSON sensitivity of a specific node in a specific network to findings at other nodes.
+func getNetNodeSensitivity(w rest.ResponseWriter, r *rest.Request) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	// Analyse sensitivity without findings
+	batch, status, err := nodeSensitivity(r, &caseJSON{Cases: []map[string]string{{}}})
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteJson(batch.Results[0])
+}
+
+// postNetNodeSensitivity returns JSON sensitivity of a specific node in a specific network to findings at other nodes
+// given JSON payload cases.
+func postNetNodeSensitivity(w rest.ResponseWriter, r *rest.Request) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	// Decode case data from JSON payload and check for errors
+	infer := new(caseJSON)
+	err := r.DecodeJsonPayload(infer)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Analyse sensitivity given each case
+	batch, status, err := nodeSensitivity(r, infer)
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteJson(batch)
+}
+
+// nodeSensitivity analyses sensitivity of the node in the network of r given each case in infer.
+// Nodes to vary are listed by the vary query parameter, or all unobserved nodes other than the target.
+// The measure is given by the kind query parameter, entropy (default) or variance.
+// Caller must hold serveJSONLock and serveLock.
+func nodeSensitivity(r *rest.Request, infer *caseJSON) (*sensBatchJSON, int, error) {
+	netID := r.PathParam("netid")
+	// Validated target network and node and check for errors
+	repr, ok := netsJSON[netID]
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("In function nodeSensitivity: network %s not loaded", netID)
+	}
+	net, status, err := neticaNet("nodeSensitivity", netID)
+	if err != nil {
+		return nil, status, err
+	}
+	node, err := lookupNeticaNode(netID, repr, r.PathParam("nodeid"))
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	// Parse sensitivity measure
+	kind := gonetica.MutualInfoSensitivity
+	kindName := r.URL.Query().Get("kind")
+	switch kindName {
+	case "", "entropy":
+		kindName = "entropy"
+	case "variance":
+		kind = gonetica.VarianceSensitivity
+	default:
+		return nil, http.StatusBadRequest, fmt.Errorf("In function nodeSensitivity: unsupported kind %s", kindName)
+	}
+	// Lookup nodes to vary by name or index, all other nodes by default
+	var varyNodes []*gonetica.Node
+	var all bool
+	if vary := r.URL.Query().Get("vary"); vary != "" {
+		for _, nodeID := range strings.Split(vary, ",") {
+			varyNode, err := lookupNeticaNode(netID, repr, strings.TrimSpace(nodeID))
+			if err != nil {
+				return nil, http.StatusNotFound, err
+			}
+			varyNodes = append(varyNodes, varyNode)
+		}
+	} else {
+		all = true
+		varyNodes, err = net.NodeList()
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+	batch := &sensBatchJSON{infer.ID, nil}
+	// Iterate over case data and build up results and check for errors
+	for index, evidence := range infer.Cases {
+		result := &sensJSON{Index: index, Kind: kindName, Ranking: []*rankJSON{}}
+		// Exclude target and observed nodes unless listed explicitly
+		var caseVary []*gonetica.Node
+		for _, varyNode := range varyNodes {
+			name := varyNode.Name()
+			if _, observed := evidence[name]; all && (observed || name == node.Name()) {
+				continue
+			}
+			caseVary = append(caseVary, varyNode)
+		}
+		// Enter case data and check for errors
+		net.Lock()
+		err = net.EnterCase(evidence)
+		if err != nil {
+			net.Unlock()
+			log.Println(err)
+			result.Error = err.Error()
+			batch.Results = append(batch.Results, result)
+			continue
+		}
+		// Measure sensitivity and check for errors
+		values, err := node.Sensitivity(caseVary, kind)
+		net.ClearCases()
+		net.Unlock()
+		if err != nil {
+			log.Println(err)
+			result.Error = err.Error()
+			batch.Results = append(batch.Results, result)
+			continue
+		}
+		// Rank nodes by descending sensitivity, skipping undefined values
+		for rank, varyNode := range caseVary {
+			if !math.IsNaN(values[rank]) {
+				result.Ranking = append(result.Ranking, &rankJSON{varyNode.Name(), values[rank]})
+			}
+		}
+		sort.SliceStable(result.Ranking, func(i, j int) bool {
+			return result.Ranking[i].Value > result.Ranking[j].Value
+		})
+		batch.Results = append(batch.Results, result)
+	}
+	return batch, http.StatusOK, nil
+}
+
+// postNetMPE returns JSON most probable configuration of all nodes in a specific network given JSON payload cases.
+func postNetMPE(w rest.ResponseWriter, r *rest.Request) {
+	// Parse rank of configuration and check for errors
+	nth := 0
+	if param := r.URL.Query().Get("nth"); param != "" {
+		var err error
+		nth, err = strconv.Atoi(param)
+		if err != nil || nth < 0 {
+			rest.Error(w, fmt.Sprintf("In function postNetMPE: invalid nth %s", param), http.StatusBadRequest)
+			return
+		}
+	}
+	queryNet(w, r, func(net *gonetica.Network, infer *caseJSON, result *configJSON) error {
+		// Find configuration and check for errors
+		nodes, err := net.NodeList()
+		if err != nil {
+			return err
+		}
+		config, err := net.MostProbableConfig(nodes, nth)
+		if err != nil {
+			return err
+		}
+		// Name state of each node and check for errors
+		result.Config = make(map[string]string)
+		for index, node := range nodes {
+			names, err := node.StateNameList()
+			if err != nil {
+				return err
+			}
+			result.Config[node.Name()] = stateLabel(names, config[index])
+		}
+		// Calculate probability of configuration and check for errors
+		prob, err := net.JointProbability(nodes, config)
+		if err != nil {
+			return err
+		}
+		result.Probability = &prob
+		return nil
+	})
+}
+
+// postNetJoint returns JSON joint probability of node states in a specific network given JSON payload cases.
+func postNetJoint(w rest.ResponseWriter, r *rest.Request) {
+	queryNet(w, r, func(net *gonetica.Network, infer *caseJSON, result *configJSON) error {
+		var nodes []*gonetica.Node
+		var states []int
+		// Lookup queried nodes and states and check for errors
+		if len(infer.Query) == 0 {
+			return fmt.Errorf("In function postNetJoint: no query node states")
+		}
+		for name, state := range infer.Query {
+			node, err := net.NodeNamed(name)
+			if err != nil {
+				return err
+			}
+			index, err := node.ParseState(state)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+			states = append(states, index)
+		}
+		result.Config = infer.Query
+		// Calculate probability of query and check for errors
+		prob, err := net.JointProbability(nodes, states)
+		if err != nil {
+			return err
+		}
+		result.Probability = &prob
+		return nil
+	})
+}
+
+// queryNet enters each case of the JSON payload into the network of r and answers query with findings entered.
+func queryNet(w rest.ResponseWriter, r *rest.Request, query func(*gonetica.Network, *caseJSON, *configJSON) error) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	// Validated target network and check for errors
+	net, status, err := neticaNet("queryNet", r.PathParam("netid"))
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	// Decode case data from JSON payload and check for errors
+	infer := new(caseJSON)
+	err = r.DecodeJsonPayload(infer)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	batch := &configBatchJSON{infer.ID, nil}
+	// Iterate over case data and build up results and check for errors
+	for index, evidence := range infer.Cases {
+		result := &configJSON{Index: index}
+		// Enter case data and check for errors
+		net.Lock()
+		err = net.EnterCase(evidence)
+		if err != nil {
+			net.Unlock()
+			log.Println(err)
+			result.Error = err.Error()
+			batch.Results = append(batch.Results, result)
+			continue
+		}
+		// Calculate probability of findings and answer query, check for errors
+		prob, err := net.FindingsProbability()
+		if err == nil {
+			result.FindingsProb = &prob
+			err = query(net, infer, result)
+		}
+		if err != nil {
+			log.Println(err)
+			result.Error = err.Error()
+		}
+		// Clear cases from network and append result to batch
+		net.ClearCases()
+		net.Unlock()
+		batch.Results = append(batch.Results, result)
+	}
+	w.WriteJson(batch)
+}
+
+// stateLabel returns the name of state index in names, or #index if unnamed.
+func stateLabel(names []string, index int) string {
+	if index >= 0 && index < len(names) && names[index] != "" {
+		return names[index]
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// postNetDecide returns JSON optimal decisions and expected utility of a specific network given JSON payload cases.
+func postNetDecide(w rest.ResponseWriter, r *rest.Request) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	// Validated target network and check for errors
+	net, status, err := neticaNet("postNetDecide", r.PathParam("netid"))
+	if err != nil {
+		rest.Error(w, err.Error(), status)
+		return
+	}
+	// Decode case data from JSON payload and check for errors
+	infer := new(caseJSON)
+	err = r.DecodeJsonPayload(infer)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Find decision nodes and check for errors
+	nodes, err := net.NodeList()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var decisions []*gonetica.Node
+	for _, node := range nodes {
+		if node.Kind() == gonetica.DecisionNode {
+			decisions = append(decisions, node)
+		}
+	}
+	if len(decisions) == 0 {
+		rest.Error(w, "In function postNetDecide: network has no decision nodes", http.StatusBadRequest)
+		return
+	}
+	batch := &decideBatchJSON{infer.ID, nil}
+	// Iterate over case data and build up results and check for errors
+	for index, evidence := range infer.Cases {
+		result := &decideJSON{Index: index, Decisions: make(map[string]*decisionJSON)}
+		// Enter case data, optimise decisions and check for errors
+		net.Lock()
+		var tables [][][]float64
+		err = net.EnterCase(evidence)
+		if err == nil {
+			tables, err = decisionTables(decisions)
+		}
+		if err == nil {
+			err = net.OptimizeDecisions()
+			if err == nil {
+				err = buildDecisions(result, net, decisions, evidence)
+			}
+			// Restore tables so policies optimised for this case are not seen by other requests
+			if restoreErr := restoreTables(decisions, tables); restoreErr != nil {
+				log.Println(restoreErr)
+			}
+		}
+		if err != nil {
+			log.Println(err)
+			result.Error = err.Error()
+		}
+		// Clear cases from network and append result to batch
+		net.ClearCases()
+		net.Unlock()
+		batch.Results = append(batch.Results, result)
+	}
+	w.WriteJson(batch)
+}
+
+// decisionTables returns the table of each decision node, nil for nodes without tables.
+func decisionTables(decisions []*gonetica.Node) ([][][]float64, error) {
+	var tables [][][]float64
+	for _, node := range decisions {
+		table, err := node.CPT()
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// restoreTables sets the table of each decision node to tables as returned by decisionTables.
+func restoreTables(decisions []*gonetica.Node, tables [][][]float64) error {
+	for index, node := range decisions {
+		var err error
+		if tables[index] == nil {
+			err = node.DeleteCPT()
+		} else {
+			err = node.SetCPT(tables[index])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDecisions adds the optimal choice at each decision node not decided in evidence and the expected utility to result.
+func buildDecisions(result *decideJSON, net *gonetica.Network, decisions []*gonetica.Node, evidence map[string]string) error {
+	for _, node := range decisions {
+		// Skip decisions already made in case
+		if _, decided := evidence[node.Name()]; decided {
+			continue
+		}
+		// Get expected utility of each choice and check for errors
+		utilities, err := node.ExpectedUtilities()
+		if err != nil {
+			return err
+		}
+		names, err := node.StateNameList()
+		if err != nil {
+			return err
+		}
+		// Choose first state with max expected utility
+		optimal := 0
+		for index, utility := range utilities {
+			if utility > utilities[optimal] {
+				optimal = index
+			}
+		}
+		result.Decisions[node.Name()] = &decisionJSON{stateLabel(names, optimal), utilities}
+	}
+	// Get expected utility of network and check for errors
+	utility, err := net.ExpectedUtility()
+	if err != nil {
+		return err
+	}
+	result.ExpectedUtility = &utility
+	return nil
+}
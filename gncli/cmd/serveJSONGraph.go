@@ -0,0 +1,94 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"github.com/slee21/gonetica"
+)
+
+// getNetGraph returns a DOT or SVG graph of a specific network, with beliefs given findings in the query.
+func getNetGraph(w rest.ResponseWriter, r *rest.Request) {
+	// Hold locks until done so reloads wait for in-flight requests
+	serveJSONLock.RLock()
+	defer serveJSONLock.RUnlock()
+	serveLock.RLock()
+	defer serveLock.RUnlock()
+	netID := r.PathParam("netid")
+	// Validated target network and check for errors
+	if _, ok := netsJSON[netID]; !ok {
+		rest.NotFound(w, r)
+		return
+	}
+	net := netLookup[netID]
+	query := r.URL.Query()
+	// Select graph format
+	var write func(io.Writer, gonetica.BackendNetwork, gonetica.GraphOptions) error
+	var contentType string
+	switch format := query.Get("format"); format {
+	case "", "svg":
+		write, contentType = gonetica.WriteSVG, "image/svg+xml"
+	case "dot":
+		write, contentType = gonetica.WriteDOT, "text/vnd.graphviz; charset=utf-8"
+	default:
+		rest.Error(w, fmt.Sprintf("In function getNetGraph: unsupported format %s", format), http.StatusBadRequest)
+		return
+	}
+	// Read options, drawing titles, findings and positions unless disabled
+	options := gonetica.GraphOptions{Titles: true, Findings: true, Positions: true}
+	for name, option := range map[string]*bool{"titles": &options.Titles, "beliefs": &options.Beliefs, "positions": &options.Positions} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				rest.Error(w, fmt.Sprintf("In function getNetGraph: invalid %s %s", name, value), http.StatusBadRequest)
+				return
+			}
+			*option = parsed
+		}
+	}
+	// Read findings as node=evidence
+	var evidence = make(map[string]string)
+	for _, finding := range query["finding"] {
+		parts := strings.SplitN(finding, "=", 2)
+		if len(parts) != 2 {
+			rest.Error(w, fmt.Sprintf("In function getNetGraph: invalid finding %s, expected node=evidence", finding), http.StatusBadRequest)
+			return
+		}
+		evidence[parts[0]] = parts[1]
+	}
+	// Enter findings, draw graph then clear findings and check for errors
+	var buf bytes.Buffer
+	net.Lock()
+	err := net.EnterCase(evidence)
+	if err == nil {
+		err = write(&buf, net, options)
+	}
+	net.ClearCases()
+	net.Unlock()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.(http.ResponseWriter).Write(buf.Bytes())
+}
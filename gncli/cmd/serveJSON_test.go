@@ -0,0 +1,302 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/spf13/viper"
+
+	"github.com/slee21/gonetica/fake"
+)
+
+// newTestNet returns the network served in tests.
+func newTestNet() *fake.Network {
+	return fake.NewNetwork("ChestClinic",
+		&fake.Node{NodeName: "Smoking", States: []string{"smoker", "nonsmoker"}, Beliefs: []float64{0.5, 0.5}},
+		&fake.Node{NodeName: "Cancer", NodeTitle: "Lung Cancer", States: []string{"present", "absent"}, Parents: []string{"Smoking"}, Beliefs: []float64{0.055, 0.945}, Table: [][]float64{{0.1, 0.9}, {0.01, 0.99}}},
+		&fake.Node{NodeName: "Cigarettes", States: []string{"none", "some"}, Levels: []float64{0, 10}, Parents: []string{"Smoking"}, Beliefs: []float64{0.5, 0.5}},
+	)
+}
+
+// newTestAPI serves the Bayesnets of a fake backend from a temporary dir through the JSON API router.
+func newTestAPI(t *testing.T) http.Handler {
+	dir, err := ioutil.TempDir("", "gncli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	return newTestDirAPI(t, dir)
+}
+
+// newTestDirAPI serves the Bayesnets of a fake backend from dir through the JSON API router.
+func newTestDirAPI(t *testing.T, dir string) http.Handler {
+	// Register network with fake backend and create its file in dir
+	backend := fake.NewBackend()
+	backend.Add("asia.dne", newTestNet())
+	backend.Add("asia.bif", newTestNet())
+	err := ioutil.WriteFile(filepath.Join(dir, "asia.dne"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Load networks and build JSON representation as on server start
+	netBackend = backend
+	netPaths, err = indexNets(netBackend, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	netList, netLookup = listNets(netPaths)
+	apiPrefix = initAPIPrefix("api")
+	netJSONList, netsJSON, err = buildJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, err := initRouter(initMiddleware(rest.NewApi()), apiPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.MakeHandler()
+}
+
+// serveTest serves a request with JSON body, if not nil, and returns the response status and decodes its body into out.
+func serveTest(t *testing.T, handler http.Handler, method string, path string, body interface{}, out interface{}) int {
+	var reader *bytes.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(buf)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if out != nil && recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %s", method, path, err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestGetNets(t *testing.T) {
+	handler := newTestAPI(t)
+	var nets []*netJSON
+	if status := serveTest(t, handler, "GET", "/api/nets", nil, &nets); status != http.StatusOK {
+		t.Fatalf("GET /api/nets: status %d", status)
+	}
+	if len(nets) != 1 || nets[0].Name != "ChestClinic" || nets[0].Index != 0 {
+		t.Errorf("GET /api/nets: got %+v", nets)
+	}
+}
+
+func TestGetNetNode(t *testing.T) {
+	handler := newTestAPI(t)
+	tests := []struct {
+		path   string
+		status int
+		name   string
+	}{
+		{"/api/nets/ChestClinic/nodes/Cancer", http.StatusOK, "Cancer"},
+		{"/api/nets/0/nodes/Cancer", http.StatusOK, "Cancer"},
+		// Nodes are indexed in name order
+		{"/api/nets/ChestClinic/nodes/2", http.StatusOK, "Smoking"},
+		{"/api/nets/ChestClinic/nodes/Dyspnea", http.StatusNotFound, ""},
+		{"/api/nets/ChestClinic/nodes/3", http.StatusNotFound, ""},
+		{"/api/nets/Alarm/nodes/Cancer", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		var node nodeJSON
+		status := serveTest(t, handler, "GET", test.path, nil, &node)
+		if status != test.status {
+			t.Errorf("GET %s: status %d, want %d", test.path, status, test.status)
+			continue
+		}
+		if status == http.StatusOK && node.Name != test.name {
+			t.Errorf("GET %s: node %s, want %s", test.path, node.Name, test.name)
+		}
+	}
+	// Check node structure
+	var node nodeJSON
+	serveTest(t, handler, "GET", "/api/nets/ChestClinic/nodes/Cancer", nil, &node)
+	if node.Title != "Lung Cancer" || node.Kind != "nature" || !node.Discrete || len(node.States) != 2 || len(node.Parents) != 1 || node.Parents[0] != "Smoking" {
+		t.Errorf("GET node Cancer: got %+v", node)
+	}
+	serveTest(t, handler, "GET", "/api/nets/ChestClinic/nodes/Smoking", nil, &node)
+	if len(node.Children) != 2 {
+		t.Errorf("GET node Smoking: children %v, want Cancer and Cigarettes", node.Children)
+	}
+	// Check table is included on request
+	var withCPT nodeJSON
+	if status := serveTest(t, handler, "GET", "/api/nets/ChestClinic/nodes/Cancer?include=cpt", nil, &withCPT); status != http.StatusOK {
+		t.Fatalf("GET node Cancer with cpt: status %d", status)
+	}
+	if len(withCPT.CPT) != 2 || len(withCPT.CPT[1]) != 2 || withCPT.CPT[1][0] != 0.01 {
+		t.Errorf("GET node Cancer with cpt: got %v", withCPT.CPT)
+	}
+}
+
+func TestPostNetInfer(t *testing.T) {
+	handler := newTestAPI(t)
+	payload := map[string]interface{}{
+		"id":      "batch",
+		"targets": []string{"Cancer", "Cigarettes"},
+		"cases":   []map[string]string{{"Smoking": "smoker"}, {"Smoking": "sometimes"}, {"Cancer": "#0"}},
+	}
+	var batch multiBatchJSON
+	if status := serveTest(t, handler, "POST", "/api/nets/ChestClinic/infer", payload, &batch); status != http.StatusOK {
+		t.Fatalf("POST infer: status %d", status)
+	}
+	if batch.ID != "batch" || len(batch.Results) != 3 {
+		t.Fatalf("POST infer: got %+v", batch)
+	}
+	// Check posterior of each target
+	first := batch.Results[0]
+	if first.Error != "" || len(first.Targets) != 2 {
+		t.Fatalf("POST infer case 0: got %+v", first)
+	}
+	if cancer := first.Targets["Cancer"]; cancer.Value != "absent" || len(cancer.Beliefs) != 2 || cancer.Beliefs[0] != 0.055 {
+		t.Errorf("POST infer case 0 Cancer: got %+v", cancer)
+	}
+	if cigarettes := first.Targets["Cigarettes"]; cigarettes.Expected == nil || *cigarettes.Expected != 5 {
+		t.Errorf("POST infer case 0 Cigarettes: got %+v", cigarettes)
+	}
+	// Check undefined state is reported for its case only
+	if batch.Results[1].Error == "" || len(batch.Results[1].Targets) != 0 {
+		t.Errorf("POST infer case 1: want error, got %+v", batch.Results[1])
+	}
+	if cancer := batch.Results[2].Targets["Cancer"]; cancer == nil || cancer.Value != "present" || cancer.Beliefs[0] != 1 {
+		t.Errorf("POST infer case 2 Cancer: got %+v", cancer)
+	}
+}
+
+func TestPostNetInferAll(t *testing.T) {
+	handler := newTestAPI(t)
+	payload := map[string]interface{}{
+		"targets": []string{"*"},
+		"cases":   []map[string]string{{"Smoking": "nonsmoker"}, {}},
+	}
+	var batch multiBatchJSON
+	if status := serveTest(t, handler, "POST", "/api/nets/0/infer", payload, &batch); status != http.StatusOK {
+		t.Fatalf("POST infer *: status %d", status)
+	}
+	// Check observed nodes are skipped
+	if targets := batch.Results[0].Targets; len(targets) != 2 || targets["Smoking"] != nil {
+		t.Errorf("POST infer * case 0: got targets %v, want Cancer and Cigarettes", targets)
+	}
+	if targets := batch.Results[1].Targets; len(targets) != 3 {
+		t.Errorf("POST infer * case 1: got targets %v, want all nodes", targets)
+	}
+}
+
+func TestPostNetInferErrors(t *testing.T) {
+	handler := newTestAPI(t)
+	tests := []struct {
+		path    string
+		payload interface{}
+		status  int
+	}{
+		{"/api/nets/Alarm/infer", map[string]interface{}{"targets": []string{"Cancer"}}, http.StatusNotFound},
+		{"/api/nets/ChestClinic/infer", map[string]interface{}{"targets": []string{"Dyspnea"}}, http.StatusNotFound},
+		{"/api/nets/ChestClinic/infer", map[string]interface{}{"targets": []string{}}, http.StatusBadRequest},
+		{"/api/nets/ChestClinic/infer", []string{"Cancer"}, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if status := serveTest(t, handler, "POST", test.path, test.payload, nil); status != test.status {
+			t.Errorf("POST %s %v: status %d, want %d", test.path, test.payload, status, test.status)
+		}
+	}
+	// Check JSON content type is required
+	request := httptest.NewRequest("POST", "/api/nets/ChestClinic/infer", bytes.NewReader([]byte(`{"targets":["Cancer"]}`)))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("POST infer without content type: status %d, want %d", recorder.Code, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestPostNetNode(t *testing.T) {
+	handler := newTestAPI(t)
+	payload := map[string]interface{}{
+		"posterior": true,
+		"cases":     []map[string]string{{"Smoking": "smoker"}, {"Smoking": "#5"}},
+	}
+	var batch batchJSON
+	if status := serveTest(t, handler, "POST", "/api/nets/ChestClinic/nodes/Smoking", payload, &batch); status != http.StatusOK {
+		t.Fatalf("POST node: status %d", status)
+	}
+	if len(batch.Results) != 2 || batch.Results[0].Value != "smoker" || batch.Results[0].FindingsProb == nil || *batch.Results[0].FindingsProb != 0.5 {
+		t.Errorf("POST node: got %+v", batch.Results)
+	}
+	if batch.Results[1].Error == "" {
+		t.Errorf("POST node case 1: want error for undefined state")
+	}
+	if status := serveTest(t, handler, "POST", "/api/nets/ChestClinic/nodes/Dyspnea", payload, nil); status != http.StatusNotFound {
+		t.Errorf("POST unknown node: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestPutNet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gncli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	viper.Set("dir", dir)
+	viper.Set("admin-token", "secret")
+	viper.Set("max-body", 1<<20)
+	defer viper.Set("admin-token", "")
+	handler := newTestDirAPI(t, dir)
+	put := func(path string) int {
+		request := httptest.NewRequest("PUT", path, bytes.NewReader([]byte("network")))
+		request.Header.Set("Authorization", "Bearer secret")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	// Check replaced network is loaded and saved in the requested format
+	if status := put("/api/nets/ChestClinic?format=bif"); status != http.StatusOK {
+		t.Fatalf("PUT net as bif: status %d", status)
+	}
+	if _, ok := netPaths["asia.bif"]; !ok || len(netPaths) != 1 {
+		t.Errorf("PUT net as bif: got paths %v, want asia.bif only", netPaths)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "asia.bif")); err != nil {
+		t.Errorf("PUT net as bif: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "asia.dne")); !os.IsNotExist(err) {
+		t.Errorf("PUT net as bif: previous file asia.dne not removed")
+	}
+	// Check networks not loaded must be addressed by name
+	if status := put("/api/nets/3"); status != http.StatusNotFound {
+		t.Errorf("PUT unloaded index: status %d, want %d", status, http.StatusNotFound)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "3.dne")); !os.IsNotExist(err) {
+		t.Errorf("PUT unloaded index: file 3.dne created")
+	}
+}
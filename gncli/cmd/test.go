@@ -0,0 +1,167 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/slee21/gonetica"
+)
+
+// testJSON is the JSON representation of the test result of a target Node.
+type testJSON struct {
+	Node          string      `json:"node"`
+	States        []string    `json:"states"`
+	ErrorRate     float64     `json:"error_rate"`
+	LogLoss       float64     `json:"log_loss"`
+	QuadraticLoss float64     `json:"quadratic_loss"`
+	Confusion     [][]float64 `json:"confusion"`
+}
+
+var (
+	testNet      string
+	testCases    string
+	testTargets  []string
+	testFormat   string
+	testMaxError float64
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test accuracy of a Bayesnet against a case file",
+	Long: `Test enters each case of a Netica case file into a Bayesnet with the target
+nodes unobserved, then reports the error rate, logarithmic loss, quadratic loss
+and confusion matrix of each target node as a table or JSON. Exits with an
+error if the error rate of any target exceeds the maximum, for use in release
+pipelines.`,
+	RunE: runTest,
+}
+
+func init() {
+	RootCmd.AddCommand(testCmd)
+
+	// Initialise flags
+	testCmd.Flags().StringVar(&testNet, "net", "", "Netica Bayesnet file to test")
+	testCmd.Flags().StringVar(&testCases, "cases", "", "comma or tab delimited Netica case file to test against")
+	testCmd.Flags().StringSliceVar(&testTargets, "targets", nil, "comma separated names of target nodes")
+	testCmd.Flags().StringVar(&testFormat, "format", "table", "output format, table or json")
+	testCmd.Flags().Float64Var(&testMaxError, "max-error", 1, "maximum error rate of any target node")
+}
+
+// runTest tests a Bayesnet against a case file and reports the results.
+func runTest(cmd *cobra.Command, args []string) error {
+	// Check required flags
+	if testNet == "" || testCases == "" || len(testTargets) == 0 {
+		return fmt.Errorf("In function test: --net, --cases and --targets are required")
+	}
+	if testFormat != "table" && testFormat != "json" {
+		return fmt.Errorf("In function test: unsupported format %s", testFormat)
+	}
+	// Initialise Netica and check for errors
+	err := initNetica(viper.GetString("license"))
+	if err != nil {
+		return err
+	}
+	defer neticaEnv.CloseEnvironment()
+	// Read Bayesnet and check for errors
+	net, err := gonetica.NewNetwork(neticaEnv, testNet)
+	if err != nil {
+		return err
+	}
+	defer net.CloseNetwork()
+	// Read case file and check for errors
+	cases, err := gonetica.NewCaseset(neticaEnv, "test")
+	if err != nil {
+		return err
+	}
+	defer cases.CloseCaseset()
+	err = cases.AddFile(testCases, 1)
+	if err != nil {
+		return err
+	}
+	// Lookup target nodes and check for errors
+	var targets []*gonetica.Node
+	for _, name := range testTargets {
+		node, err := net.NodeNamed(name)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, node)
+	}
+	// Test network and check for errors
+	results, err := net.Test(cases, targets)
+	if err != nil {
+		return err
+	}
+	// Build JSON representation of results and check for errors
+	var reprs []*testJSON
+	for _, result := range results {
+		states, err := result.Node.StateNameList()
+		if err != nil {
+			return err
+		}
+		reprs = append(reprs, &testJSON{result.Node.Name(), states, result.ErrorRate, result.LogLoss, result.QuadraticLoss, result.Confusion})
+	}
+	// Write results in format and check for errors
+	if testFormat == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(reprs)
+	} else {
+		err = writeTestTable(os.Stdout, reprs)
+	}
+	if err != nil {
+		return err
+	}
+	// Check error rate of each target
+	for _, repr := range reprs {
+		if repr.ErrorRate > testMaxError {
+			return fmt.Errorf("In function test: error rate %g of node %s exceeds maximum %g", repr.ErrorRate, repr.Node, testMaxError)
+		}
+	}
+	return nil
+}
+
+// writeTestTable writes a summary table of reprs followed by the confusion matrix of each target.
+func writeTestTable(w io.Writer, reprs []*testJSON) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	// Write summary row per target
+	fmt.Fprintln(table, "NODE\tERROR RATE\tLOG LOSS\tQUADRATIC LOSS")
+	for _, repr := range reprs {
+		fmt.Fprintf(table, "%s\t%.4f\t%.4f\t%.4f\n", repr.Node, repr.ErrorRate, repr.LogLoss, repr.QuadraticLoss)
+	}
+	// Write confusion matrix per target with predicted states as rows
+	for _, repr := range reprs {
+		fmt.Fprintf(table, "\n%s PREDICTED \\ ACTUAL\t%s\n", repr.Node, strings.Join(repr.States, "\t"))
+		for index, row := range repr.Confusion {
+			var counts []string
+			for _, count := range row {
+				counts = append(counts, fmt.Sprintf("%g", count))
+			}
+			fmt.Fprintf(table, "%s\t%s\n", repr.States[index], strings.Join(counts, "\t"))
+		}
+	}
+	return table.Flush()
+}
@@ -0,0 +1,225 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/slee21/gonetica"
+)
+
+// watchDelay is how long a Bayesnet file must be left unchanged before it is reloaded.
+const watchDelay = 500 * time.Millisecond
+
+var (
+	watchTimers = make(map[string]*time.Timer)
+	watchLock   sync.Mutex
+
+	// watchSaved holds the modification times of files written by the server itself, which are not reloaded.
+	watchSaved = make(map[string]time.Time)
+)
+
+// watchNets watches dir recursively and reloads Bayesnets as files change.
+// Every change to the loaded Networks is passed as swap to reload, which must apply it atomically.
+func watchNets(dir string, reload func(swap func())) error {
+	root := filepath.Clean(dir)
+	// Initialise file system watcher and check for errors
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Watch root and all subdirectories and check for errors
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	// Handle file system events in background
+	go func() {
+		for {
+			select {
+			case event := <-watcher.Events:
+				handleNetEvent(watcher, root, event, reload)
+			case err := <-watcher.Errors:
+				log.Println(err)
+			}
+		}
+	}()
+	return nil
+}
+
+// handleNetEvent schedules Bayesnets affected by a file system event for reloading.
+func handleNetEvent(watcher *fsnotify.Watcher, root string, event fsnotify.Event, reload func(swap func())) {
+	// Watch created directories and schedule contained Bayesnets
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		if event.Op&fsnotify.Create == fsnotify.Create {
+			filepath.Walk(event.Name, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if info.IsDir() {
+					if err := watcher.Add(path); err != nil {
+						log.Println(err)
+					}
+				} else if isNetFile(path) {
+					scheduleNet(root, path, reload)
+				}
+				return nil
+			})
+		}
+		return
+	}
+	// Schedule changed Bayesnets and removed paths which may be directories
+	if isNetFile(event.Name) || event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		scheduleNet(root, event.Name, reload)
+	}
+}
+
+// scheduleNet reloads path once it has been left unchanged for watchDelay.
+func scheduleNet(root string, path string, reload func(swap func())) {
+	watchLock.Lock()
+	defer watchLock.Unlock()
+	// Restart timer if path changed again before reloading
+	if timer, ok := watchTimers[path]; ok {
+		timer.Stop()
+	}
+	watchTimers[path] = time.AfterFunc(watchDelay, func() {
+		watchLock.Lock()
+		delete(watchTimers, path)
+		watchLock.Unlock()
+		syncNet(root, path, reload)
+	})
+}
+
+// syncNet loads, replaces or unloads the Bayesnets at path to match the file system.
+func syncNet(root string, path string, reload func(swap func())) {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	// Skip files left as written by the server, whose Networks are already loaded
+	if isSavedNet(path) {
+		return
+	}
+	// Unload Bayesnets at or below removed path
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		reload(func() {
+			unloadNets(relPath)
+		})
+		return
+	}
+	// Load or replace Bayesnet from file
+	if isNetFile(path) {
+		reload(func() {
+			replaceNet(root, relPath)
+		})
+	}
+}
+
+// markSavedNet records that the server wrote the file at path so the resulting events are not reloaded.
+func markSavedNet(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	watchLock.Lock()
+	defer watchLock.Unlock()
+	watchSaved[path] = info.ModTime()
+}
+
+// isSavedNet returns whether the file at path is unchanged since the server wrote it.
+func isSavedNet(path string) bool {
+	watchLock.Lock()
+	defer watchLock.Unlock()
+	saved, ok := watchSaved[path]
+	if !ok {
+		return false
+	}
+	delete(watchSaved, path)
+	info, err := os.Stat(path)
+	return err == nil && info.ModTime().Equal(saved)
+}
+
+// replaceNet reads Bayesnet at relPath under root, replacing any Network previously loaded from it.
+// The previous Network is kept if the file cannot be read or its name is already loaded from another path.
+func replaceNet(root string, relPath string) {
+	serveLock.Lock()
+	defer serveLock.Unlock()
+	// Read file into Bayesnet and check for errors
+	net, err := gonetica.LoadFile(netBackend, filepath.Join(root, relPath), netOptions(relPath))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	// Index network by path and check for errors
+	if err = indexNet(relPath, net); err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("Loaded network %s from path %s\n", net.Name(), relPath)
+}
+
+// indexNet indexes net by relPath, closing any Network previously loaded from it.
+// Closes net instead if its name is already loaded from another path. Caller must hold serveLock.
+func indexNet(relPath string, net gonetica.BackendNetwork) error {
+	// Check if network with name already loaded from another path
+	name := net.Name()
+	for otherPath, other := range netPaths {
+		if otherPath != relPath && other.Name() == name {
+			net.CloseNetwork()
+			return fmt.Errorf("In function indexNet: network named %s already loaded from path %s", name, otherPath)
+		}
+	}
+	// Close previous version and index new version
+	if old, ok := netPaths[relPath]; ok {
+		old.CloseNetwork()
+	}
+	netPaths[relPath] = net
+	netList, netLookup = listNets(netPaths)
+	return nil
+}
+
+// unloadNets closes Networks loaded from relPath or from files below it.
+func unloadNets(relPath string) {
+	serveLock.Lock()
+	defer serveLock.Unlock()
+	// Close and remove matching networks from index
+	for path, net := range netPaths {
+		if path == relPath || strings.HasPrefix(path, relPath+string(filepath.Separator)) {
+			log.Printf("Unloaded network %s from path %s\n", net.Name(), path)
+			net.CloseNetwork()
+			delete(netPaths, path)
+		}
+	}
+	netList, netLookup = listNets(netPaths)
+}
@@ -0,0 +1,405 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonetica
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GraphOptions select what WriteDOT and WriteSVG draw for each node of a Bayesnet.
+type GraphOptions struct {
+	// Titles labels nodes with their titles instead of their names where set.
+	Titles bool
+	// Beliefs lists the belief in each state of a node below its label.
+	Beliefs bool
+	// Findings highlights nodes with findings entered.
+	Findings bool
+	// Positions places nodes at their positions in the visual display of the Bayesnet, if every node has one.
+	Positions bool
+}
+
+// Positioner is implemented by nodes with a position in the visual display of their Bayesnet.
+type Positioner interface {
+	// Position returns the center of the node, ok is false if the node has no position.
+	Position() (x float64, y float64, ok bool)
+}
+
+// FindingHolder is implemented by nodes that report whether a finding is entered.
+type FindingHolder interface {
+	HasFinding() bool
+}
+
+// graphNode is a node of a Bayesnet as drawn, with its label lines, size and center.
+type graphNode struct {
+	name    string
+	lines   []string
+	beliefs []float64
+	finding bool
+	parents []int
+	x, y    float64
+	width   float64
+	height  float64
+	placed  bool
+}
+
+// Sizes of drawn nodes and gaps between them, in points.
+const (
+	graphCharWidth  = 7.0
+	graphLineHeight = 16.0
+	graphPadding    = 8.0
+	graphGapX       = 30.0
+	graphGapY       = 60.0
+	graphMargin     = 20.0
+)
+
+// WriteDOT writes the nodes and links of net to w as a GraphViz DOT graph.
+// Node positions are pinned with pos attributes, which neato and fdp honour.
+func WriteDOT(w io.Writer, net BackendNetwork, options GraphOptions) error {
+	nodes, err := buildGraph(net, options)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %s {\n", dotQuote(net.Name()))
+	if options.Beliefs {
+		fmt.Fprintf(out, "\tnode [shape=record];\n")
+	}
+	for _, node := range nodes {
+		var attrs []string
+		if options.Beliefs {
+			// List state beliefs below title in record fields
+			fields := []string{dotRecordEscape(node.lines[0])}
+			var states []string
+			for _, line := range node.lines[1:] {
+				states = append(states, dotRecordEscape(line)+"\\l")
+			}
+			if len(states) > 0 {
+				fields = append(fields, strings.Join(states, ""))
+			}
+			attrs = append(attrs, "label="+dotQuote("{"+strings.Join(fields, "|")+"}"))
+		} else {
+			attrs = append(attrs, "label="+dotQuote(node.lines[0]))
+		}
+		if node.placed {
+			attrs = append(attrs, "pos="+dotQuote(fmt.Sprintf("%s,%s!", formatPoint(node.x), formatPoint(-node.y))))
+		}
+		if node.finding {
+			attrs = append(attrs, "style=\"filled,bold\"", "fillcolor=\"#fde68a\"")
+		}
+		fmt.Fprintf(out, "\t%s [%s];\n", dotQuote(node.name), strings.Join(attrs, ", "))
+	}
+	for _, node := range nodes {
+		for _, parent := range node.parents {
+			fmt.Fprintf(out, "\t%s -> %s;\n", dotQuote(nodes[parent].name), dotQuote(node.name))
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+	return out.Flush()
+}
+
+// WriteSVG writes the nodes and links of net to w as an SVG image.
+// Nodes without positions are laid out in layers, each node below its parents.
+func WriteSVG(w io.Writer, net BackendNetwork, options GraphOptions) error {
+	nodes, err := buildGraph(net, options)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 || !nodes[0].placed {
+		layoutGraph(nodes)
+	}
+	// Shift drawing to start at margin
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, node := range nodes {
+		minX, minY = math.Min(minX, node.x-node.width/2), math.Min(minY, node.y-node.height/2)
+		maxX, maxY = math.Max(maxX, node.x+node.width/2), math.Max(maxY, node.y+node.height/2)
+	}
+	if len(nodes) == 0 {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+	for _, node := range nodes {
+		node.x += graphMargin - minX
+		node.y += graphMargin - minY
+	}
+	width, height := maxX-minX+2*graphMargin, maxY-minY+2*graphMargin
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"0 0 %s %s\" font-family=\"sans-serif\" font-size=\"12\">\n",
+		formatPoint(width), formatPoint(height), formatPoint(width), formatPoint(height))
+	fmt.Fprintf(out, "<title>%s</title>\n", html.EscapeString(net.Name()))
+	fmt.Fprintf(out, "<defs><marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\"><path d=\"M0,0 L10,5 L0,10 z\" fill=\"#555\"/></marker></defs>\n")
+	// Draw links between node borders below nodes
+	for _, node := range nodes {
+		for _, index := range node.parents {
+			parent := nodes[index]
+			x1, y1 := borderPoint(parent, node.x, node.y)
+			x2, y2 := borderPoint(node, parent.x, parent.y)
+			fmt.Fprintf(out, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\" stroke=\"#555\" marker-end=\"url(#arrow)\"/>\n",
+				formatPoint(x1), formatPoint(y1), formatPoint(x2), formatPoint(y2))
+		}
+	}
+	for _, node := range nodes {
+		left, top := node.x-node.width/2, node.y-node.height/2
+		fill, stroke := "#ffffff", "#333333"
+		if node.finding {
+			fill, stroke = "#fde68a", "#b45309"
+		}
+		fmt.Fprintf(out, "<g><title>%s</title>\n", html.EscapeString(node.name))
+		fmt.Fprintf(out, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" rx=\"6\" fill=\"%s\" stroke=\"%s\"/>\n",
+			formatPoint(left), formatPoint(top), formatPoint(node.width), formatPoint(node.height), fill, stroke)
+		fmt.Fprintf(out, "<text x=\"%s\" y=\"%s\" text-anchor=\"middle\" font-weight=\"bold\">%s</text>\n",
+			formatPoint(node.x), formatPoint(top+graphPadding/2+graphLineHeight-4), html.EscapeString(node.lines[0]))
+		// Draw belief bars behind state lines
+		for index, line := range node.lines[1:] {
+			lineTop := top + graphPadding/2 + float64(index+1)*graphLineHeight
+			if index < len(node.beliefs) {
+				fmt.Fprintf(out, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"#93c5fd\"/>\n",
+					formatPoint(left+graphPadding/2), formatPoint(lineTop+2), formatPoint((node.width-graphPadding)*node.beliefs[index]), formatPoint(graphLineHeight-4))
+			}
+			fmt.Fprintf(out, "<text x=\"%s\" y=\"%s\">%s</text>\n",
+				formatPoint(left+graphPadding), formatPoint(lineTop+graphLineHeight-4), html.EscapeString(line))
+		}
+		fmt.Fprintf(out, "</g>\n")
+	}
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+// buildGraph returns the nodes of net as drawn with options, with parents by index.
+func buildGraph(net BackendNetwork, options GraphOptions) ([]*graphNode, error) {
+	list, err := net.NodeList()
+	if err != nil {
+		return nil, err
+	}
+	var indexes = make(map[string]int)
+	for index, node := range list {
+		indexes[node.Name()] = index
+	}
+	var nodes []*graphNode
+	placed := options.Positions
+	for _, node := range list {
+		drawn := &graphNode{name: node.Name(), lines: []string{node.Name()}}
+		if title := node.Title(); options.Titles && title != "" {
+			drawn.lines[0] = title
+		}
+		// Add line of belief in each state
+		if options.Beliefs {
+			labels, err := stateLabels(node)
+			if err != nil {
+				return nil, err
+			}
+			beliefs, err := node.BeliefList()
+			if err != nil {
+				return nil, err
+			}
+			for index, belief := range beliefs {
+				if index < len(labels) {
+					drawn.lines = append(drawn.lines, fmt.Sprintf("%s %.1f%%", labels[index], 100*belief))
+				}
+			}
+			drawn.beliefs = beliefs
+		}
+		if holder, ok := node.(FindingHolder); ok && options.Findings {
+			drawn.finding = holder.HasFinding()
+		}
+		// Use positions only if every node has one
+		if positioner, ok := node.(Positioner); ok && placed {
+			drawn.x, drawn.y, drawn.placed = positioner.Position()
+		}
+		placed = placed && drawn.placed
+		parents, err := node.ParentList()
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			drawn.parents = append(drawn.parents, indexes[parent.Name()])
+		}
+		// Size node to fit its longest line
+		longest := 0
+		for _, line := range drawn.lines {
+			if length := len([]rune(line)); length > longest {
+				longest = length
+			}
+		}
+		drawn.width = float64(longest)*graphCharWidth + 2*graphPadding
+		drawn.height = float64(len(drawn.lines))*graphLineHeight + graphPadding
+		nodes = append(nodes, drawn)
+	}
+	for _, node := range nodes {
+		node.placed = node.placed && placed
+	}
+	return nodes, nil
+}
+
+// stateLabels returns the state names of node, its intervals if continuous, or #index if unnamed.
+func stateLabels(node BackendNode) ([]string, error) {
+	names, err := node.StateNameList()
+	if err != nil {
+		return nil, err
+	}
+	levels, err := node.LevelList()
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	for index, name := range names {
+		switch {
+		case name != "":
+			labels = append(labels, name)
+		case !node.IsDiscreteType() && index+1 < len(levels):
+			labels = append(labels, fmt.Sprintf("[%g, %g)", levels[index], levels[index+1]))
+		default:
+			labels = append(labels, "#"+strconv.Itoa(index))
+		}
+	}
+	return labels, nil
+}
+
+// layoutGraph places nodes in layers below their parents, ordering each layer
+// by the mean position of linked nodes to reduce crossings.
+func layoutGraph(nodes []*graphNode) {
+	if len(nodes) == 0 {
+		return
+	}
+	// Assign each node the layer below its deepest parent
+	var layerOf = make([]int, len(nodes))
+	var visiting = make([]bool, len(nodes))
+	var done = make([]bool, len(nodes))
+	var assign func(index int) int
+	assign = func(index int) int {
+		if done[index] || visiting[index] {
+			return layerOf[index]
+		}
+		visiting[index] = true
+		for _, parent := range nodes[index].parents {
+			if layer := assign(parent) + 1; layer > layerOf[index] {
+				layerOf[index] = layer
+			}
+		}
+		visiting[index], done[index] = false, true
+		return layerOf[index]
+	}
+	var layers [][]int
+	for index := range nodes {
+		layer := assign(index)
+		for len(layers) <= layer {
+			layers = append(layers, nil)
+		}
+		layers[layer] = append(layers[layer], index)
+	}
+	// Link each node to its children for upward sweeps
+	var children = make([][]int, len(nodes))
+	for index, node := range nodes {
+		for _, parent := range node.parents {
+			children[parent] = append(children[parent], index)
+		}
+	}
+	var order = make([]float64, len(nodes))
+	number := func() {
+		for _, layer := range layers {
+			for position, index := range layer {
+				order[index] = float64(position)
+			}
+		}
+	}
+	// sortLayer orders layer by the mean position of each node's links, keeping nodes without links in place
+	sortLayer := func(layer []int, links [][]int) {
+		var keys = make(map[int]float64)
+		for _, index := range layer {
+			keys[index] = order[index]
+			if len(links[index]) > 0 {
+				sum := 0.0
+				for _, link := range links[index] {
+					sum += order[link]
+				}
+				keys[index] = sum / float64(len(links[index]))
+			}
+		}
+		sort.SliceStable(layer, func(i, j int) bool { return keys[layer[i]] < keys[layer[j]] })
+	}
+	var parents = make([][]int, len(nodes))
+	for index, node := range nodes {
+		parents[index] = node.parents
+	}
+	number()
+	for sweep := 0; sweep < 4; sweep++ {
+		for _, layer := range layers[1:] {
+			sortLayer(layer, parents)
+			number()
+		}
+		for layer := len(layers) - 2; layer >= 0; layer-- {
+			sortLayer(layers[layer], children)
+			number()
+		}
+	}
+	// Place layers centered on the widest, each as tall as its tallest node
+	var widths = make([]float64, len(layers))
+	widest := 0.0
+	for layer, indexes := range layers {
+		for _, index := range indexes {
+			widths[layer] += nodes[index].width + graphGapX
+		}
+		widest = math.Max(widest, widths[layer])
+	}
+	top := 0.0
+	for layer, indexes := range layers {
+		tallest := 0.0
+		for _, index := range indexes {
+			tallest = math.Max(tallest, nodes[index].height)
+		}
+		left := (widest - widths[layer]) / 2
+		for _, index := range indexes {
+			node := nodes[index]
+			node.x, node.y = left+node.width/2, top+tallest/2
+			left += node.width + graphGapX
+		}
+		top += tallest + graphGapY
+	}
+}
+
+// borderPoint returns where the line from the center of node towards x, y crosses its border.
+func borderPoint(node *graphNode, x float64, y float64) (float64, float64) {
+	dx, dy := x-node.x, y-node.y
+	if dx == 0 && dy == 0 {
+		return node.x, node.y
+	}
+	scale := math.Min(node.width/2/math.Abs(dx), node.height/2/math.Abs(dy))
+	return node.x + dx*scale, node.y + dy*scale
+}
+
+// formatPoint formats a coordinate with at most two decimals.
+func formatPoint(value float64) string {
+	text := strconv.FormatFloat(value, 'f', 2, 64)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	if text == "-0" {
+		return "0"
+	}
+	return text
+}
+
+// dotQuote returns text as a quoted DOT identifier, keeping backslash escapes of labels.
+func dotQuote(text string) string {
+	return "\"" + strings.NewReplacer("\"", "\\\"", "\n", "\\n").Replace(text) + "\""
+}
+
+// dotRecordEscape escapes the characters of text that delimit fields in a DOT record label.
+func dotRecordEscape(text string) string {
+	return strings.NewReplacer("{", "\\{", "}", "\\}", "|", "\\|", "<", "\\<", ">", "\\>").Replace(text)
+}
@@ -0,0 +1,55 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonetica
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLayoutGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		parents [][]int
+	}{
+		{"no nodes", nil},
+		{"single node", [][]int{nil}},
+		{"chain", [][]int{nil, {0}, {1}}},
+		{"diamond", [][]int{nil, {0}, {0}, {1, 2}}},
+		{"parents listed after children", [][]int{{2}, {0, 2}, nil}},
+		{"disconnected", [][]int{nil, nil, {0}, nil}},
+	}
+	for _, test := range tests {
+		var nodes []*graphNode
+		for index, parents := range test.parents {
+			nodes = append(nodes, &graphNode{parents: parents, width: float64(40 + 10*index), height: 24})
+		}
+		layoutGraph(nodes)
+		for index, node := range nodes {
+			// Check each node is drawn below its parents
+			for _, parent := range node.parents {
+				if bottom := nodes[parent].y + nodes[parent].height/2; node.y-node.height/2 <= bottom {
+					t.Errorf("%s: node %d at y %g not below parent %d at y %g", test.name, index, node.y, parent, nodes[parent].y)
+				}
+			}
+			// Check nodes of a layer do not overlap
+			for other := index + 1; other < len(nodes); other++ {
+				if nodes[other].y == node.y && math.Abs(nodes[other].x-node.x) < (node.width+nodes[other].width)/2 {
+					t.Errorf("%s: nodes %d and %d overlap", test.name, index, other)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonetica_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/slee21/gonetica"
+	"github.com/slee21/gonetica/native"
+)
+
+// lawnNet is a Bayesnet with titles holding record label delimiters and a position for every node.
+const lawnNet = `// ~->[DNET-1]->~
+bnet Lawn {
+node Rain {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (yes, no);
+	parents = ();
+	probs = (0.2, 0.8);
+	title = "Rain {today}";
+	visual V1 {
+		center = (100, 40);
+		};
+	};
+node Sprinkler {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (on, off);
+	parents = ();
+	probs = (0.4, 0.6);
+	title = "Sprinkler | \"on\"";
+	visual V1 {
+		center = (260, 40);
+		};
+	};
+node Wet {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (yes, no);
+	parents = (Rain, Sprinkler);
+	probs = ((0.99, 0.01), (0.8, 0.2), (0.9, 0.1), (0, 1));
+	title = "Wet Grass";
+	visual V1 {
+		center = (180, 140);
+		};
+	};
+};
+`
+
+// loadGraphNet loads src with the native backend and enters findings.
+func loadGraphNet(t *testing.T, src string, findings map[string]string) gonetica.BackendNetwork {
+	net, err := native.NewBackend().Load("graph.dne", []byte(src), gonetica.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := net.EnterCase(findings); err != nil {
+		t.Fatal(err)
+	}
+	return net
+}
+
+func TestWriteDOT(t *testing.T) {
+	unplaced := strings.Replace(lawnNet, "visual V1 {\n\t\tcenter = (260, 40);\n\t\t};\n", "", 1)
+	tests := []struct {
+		name     string
+		src      string
+		options  gonetica.GraphOptions
+		contains []string
+		excludes []string
+	}{
+		{
+			"names", lawnNet, gonetica.GraphOptions{},
+			[]string{
+				"digraph \"Lawn\" {\n",
+				"\t\"Rain\" [label=\"Rain\"];\n",
+				"\t\"Rain\" -> \"Wet\";\n",
+				"\t\"Sprinkler\" -> \"Wet\";\n",
+			},
+			[]string{"shape=record", "pos=", "filled"},
+		},
+		{
+			"titles beliefs findings and positions", lawnNet, gonetica.GraphOptions{Titles: true, Beliefs: true, Findings: true, Positions: true},
+			[]string{
+				"\tnode [shape=record];\n",
+				"\t\"Rain\" [label=\"{Rain \\{today\\}|yes 100.0%\\lno 0.0%\\l}\", pos=\"100,-40!\", style=\"filled,bold\", fillcolor=\"#fde68a\"];\n",
+				"\t\"Sprinkler\" [label=\"{Sprinkler \\| \\\"on\\\"|on 40.0%\\loff 60.0%\\l}\", pos=\"260,-40!\"];\n",
+				// P(Wet | Rain yes) = 0.4*0.99 + 0.6*0.8
+				"\t\"Wet\" [label=\"{Wet Grass|yes 87.6%\\lno 12.4%\\l}\", pos=\"180,-140!\"];\n",
+			},
+			nil,
+		},
+		{
+			"titles without records", lawnNet, gonetica.GraphOptions{Titles: true},
+			[]string{"\t\"Sprinkler\" [label=\"Sprinkler | \\\"on\\\"\"];\n"},
+			nil,
+		},
+		{
+			"positions only if every node has one", unplaced, gonetica.GraphOptions{Positions: true},
+			[]string{"\t\"Wet\" [label=\"Wet\"];\n"},
+			[]string{"pos="},
+		},
+		{
+			"no nodes", "// ~->[DNET-1]->~\nbnet Empty {\n};\n", gonetica.GraphOptions{Beliefs: true, Positions: true},
+			[]string{"digraph \"Empty\" {\n\tnode [shape=record];\n}\n"},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		net := loadGraphNet(t, test.src, map[string]string{"Rain": "yes"})
+		var buf bytes.Buffer
+		if err := gonetica.WriteDOT(&buf, net, test.options); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		for _, text := range test.contains {
+			if !strings.Contains(buf.String(), text) {
+				t.Errorf("%s: output lacks %q:\n%s", test.name, text, buf.String())
+			}
+		}
+		for _, text := range test.excludes {
+			if strings.Contains(buf.String(), text) {
+				t.Errorf("%s: output has %q:\n%s", test.name, text, buf.String())
+			}
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		options  gonetica.GraphOptions
+		contains []string
+	}{
+		{
+			// Rain and Sprinkler are laid out side by side above Wet
+			"laid out DAG", lawnNet, gonetica.GraphOptions{},
+			[]string{
+				"width=\"193\" height=\"148\"",
+				"<title>Lawn</title>\n",
+				"<rect x=\"20\" y=\"20\" width=\"44\" height=\"24\" rx=\"6\" fill=\"#ffffff\" stroke=\"#333333\"/>\n",
+				"<rect x=\"94\" y=\"20\" width=\"79\" height=\"24\" rx=\"6\" fill=\"#ffffff\" stroke=\"#333333\"/>\n",
+				"<rect x=\"78\" y=\"104\" width=\"37\" height=\"24\" rx=\"6\" fill=\"#ffffff\" stroke=\"#333333\"/>\n",
+				"<line x1=\"49.79\" y1=\"44\" x2=\"88.71\" y2=\"104\" stroke=\"#555\" marker-end=\"url(#arrow)\"/>\n",
+				"<line x1=\"128.21\" y1=\"44\" x2=\"101.79\" y2=\"104\" stroke=\"#555\" marker-end=\"url(#arrow)\"/>\n",
+			},
+		},
+		{
+			"escaped titles and findings", lawnNet, gonetica.GraphOptions{Titles: true, Beliefs: true, Findings: true, Positions: true},
+			[]string{
+				"font-weight=\"bold\">Sprinkler | &#34;on&#34;</text>\n",
+				// Positions are kept, shifted so the drawing starts at the margin
+				"<rect x=\"20\" y=\"20\" width=\"100\" height=\"56\" rx=\"6\" fill=\"#fde68a\" stroke=\"#b45309\"/>\n",
+				"<rect x=\"24\" y=\"42\" width=\"92\" height=\"12\" fill=\"#93c5fd\"/>\n<text x=\"28\" y=\"52\">yes 100.0%</text>\n",
+			},
+		},
+		{
+			"no nodes", "// ~->[DNET-1]->~\nbnet Empty {\n};\n", gonetica.GraphOptions{},
+			[]string{"width=\"40\" height=\"40\"", "<title>Empty</title>\n"},
+		},
+	}
+	for _, test := range tests {
+		net := loadGraphNet(t, test.src, map[string]string{"Rain": "yes"})
+		var buf bytes.Buffer
+		if err := gonetica.WriteSVG(&buf, net, test.options); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !strings.HasSuffix(buf.String(), "</svg>\n") {
+			t.Errorf("%s: output not closed:\n%s", test.name, buf.String())
+		}
+		for _, text := range test.contains {
+			if !strings.Contains(buf.String(), text) {
+				t.Errorf("%s: output lacks %q:\n%s", test.name, text, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+/*
+#cgo darwin CFLAGS: -I"${SRCDIR}/cgo/lib/darwin"
+#cgo darwin,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/darwin/amd64"
+#cgo darwin LDFLAGS: -lm -lnetica -lpthread -lstdc++
+#cgo linux CFLAGS: -I"${SRCDIR}/cgo/lib/linux"
+#cgo linux,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/386"
+#cgo linux,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/amd64"
+#cgo linux LDFLAGS: -lm -lrt -lnetica -lpthread -lstdc++
+#cgo windows CFLAGS: -I"${SRCDIR}/cgo/lib/windows"
+#cgo windows,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/386"
+#cgo windows,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/amd64"
+#cgo windows LDFLAGS: -lm -llibNetica -lpthread -lstdc++
+#include "stdlib.h"
+#include "Netica.h"
+*/
+import "C"
+
+import "fmt"
+
+// LearnMethod is the algorithm used by a Learner to learn conditional probability tables.
+type LearnMethod int
+
+// Learning algorithms supported by Netica.
+const (
+	CountingLearning        LearnMethod = C.COUNTING_LEARNING
+	EMLearning              LearnMethod = C.EM_LEARNING
+	GradientDescentLearning LearnMethod = C.GRADIENT_DESCENT_LEARNING
+)
+
+// Learner learns conditional probability tables of Networks from a Caseset.
+type Learner struct {
+	c *C.learner_bn
+
+	env *Environment
+}
+
+// NewLearner returns a new Learner using method.
+func NewLearner(environment *Environment, method LearnMethod) (*Learner, error) {
+	var learner = new(Learner)
+	learner.env = environment
+	// Create Netica learner and check for errors
+	learner.c = C.NewLearner_bn(C.learn_method_bn(method), nil, learner.env.c)
+	if err := learner.Errors(); err != nil {
+		return nil, err
+	}
+	return learner, nil
+}
+
+// CloseLearner closes the Learner, freeing resources.
+func (learner *Learner) CloseLearner() error {
+	C.DeleteLearner_bn(learner.c)
+	return learner.Errors()
+}
+
+// Errors returns all Netica errors of severity level error since it was last called.
+func (learner *Learner) Errors() error {
+	return learner.env.Errors()
+}
+
+// SetMaxIters sets the maximum number of iterations of EM and gradient descent learning.
+func (learner *Learner) SetMaxIters(maxIters int) error {
+	C.SetLearnerMaxIters_bn(learner.c, C.int(maxIters))
+	return learner.Errors()
+}
+
+// SetMaxTol sets the change in log likelihood below which EM and gradient descent learning stop.
+func (learner *Learner) SetMaxTol(tol float64) error {
+	C.SetLearnerMaxTol_bn(learner.c, C.double(tol))
+	return learner.Errors()
+}
+
+// LearnCPTs learns the conditional probability tables of nodes from cases, each case counted degree times.
+// Existing tables are used as prior experience, delete them first to learn from cases alone.
+func (learner *Learner) LearnCPTs(nodes []*Node, cases *Caseset, degree float64) error {
+	// Check nodes are given, all from one network
+	if len(nodes) == 0 {
+		return fmt.Errorf("In function Learner.LearnCPTs: no nodes to learn")
+	}
+	net := nodes[0].Net
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(nodes)
+	if err != nil {
+		return err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Learn tables and check for errors
+	C.LearnCPTs_bn(learner.c, cNodes, cases.c, C.double(degree))
+	net.markStale()
+	net.emit(TableEvent)
+	return learner.Errors()
+}
+
+// Learn learns the conditional probability tables of all nodes in net from cases.
+func (learner *Learner) Learn(net *Network, cases *Caseset) error {
+	// Get nodes of network and check for errors
+	nodes, err := net.NodeList()
+	if err != nil {
+		return err
+	}
+	return learner.LearnCPTs(nodes, cases, 1)
+}
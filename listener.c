@@ -0,0 +1,37 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+#include "Netica.h"
+#include "_cgo_export.h"
+
+// nodeListener forwards Netica node events to the Go registry.
+static int nodeListener(const node_bn* node, eventtype_ns what, void* object, void* info) {
+	return goNodeEvent((node_bn*)node, (int)what);
+}
+
+// netListener forwards Netica network events to the Go registry.
+static int netListener(const net_bn* net, eventtype_ns what, void* object, void* info) {
+	return goNetEvent((net_bn*)net, (int)what);
+}
+
+void addNodeListener(node_bn* node, int filter) {
+	AddNodeListener_bn(node, nodeListener, NULL, filter);
+}
+
+void addNetListener(net_bn* net, int filter) {
+	AddNetListener_bn(net, netListener, NULL, filter);
+}
@@ -0,0 +1,319 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+/*
+#cgo darwin CFLAGS: -I"${SRCDIR}/cgo/lib/darwin"
+#cgo darwin,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/darwin/amd64"
+#cgo darwin LDFLAGS: -lm -lnetica -lpthread -lstdc++
+#cgo linux CFLAGS: -I"${SRCDIR}/cgo/lib/linux"
+#cgo linux,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/386"
+#cgo linux,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/amd64"
+#cgo linux LDFLAGS: -lm -lrt -lnetica -lpthread -lstdc++
+#cgo windows CFLAGS: -I"${SRCDIR}/cgo/lib/windows"
+#cgo windows,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/386"
+#cgo windows,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/amd64"
+#cgo windows LDFLAGS: -lm -llibNetica -lpthread -lstdc++
+#include "stdlib.h"
+#include "Netica.h"
+
+void addNodeListener(node_bn* node, int filter);
+void addNetListener(net_bn* net, int filter);
+*/
+import "C"
+import (
+	"sync"
+	"unsafe"
+)
+
+// EventKind is the kind of change to a Node or Network reported to listeners.
+// Kinds may be combined with | to listen for several at once.
+type EventKind int
+
+// Event kinds raised by Netica.
+const (
+	// CreateEvent is raised when a Node or Network is created.
+	CreateEvent EventKind = C.CREATE_EVENT
+	// DuplicateEvent is raised when a Node or Network is duplicated.
+	DuplicateEvent EventKind = C.DUPLICATE_EVENT
+	// RemoveEvent is raised when a Node or Network is deleted, after which its listeners are removed.
+	RemoveEvent EventKind = C.REMOVE_EVENT
+)
+
+// Event kinds raised by gonetica around calls into Netica.
+const (
+	// FindingsEvent is raised when findings are entered or retracted.
+	FindingsEvent EventKind = 0x100 << iota
+	// TableEvent is raised when a conditional probability table is set, learnt or deleted.
+	TableEvent
+	// BeliefsEvent is raised on a Network when beliefs are updated after findings or tables changed.
+	BeliefsEvent
+
+	// AllEvents matches every kind of event.
+	AllEvents = CreateEvent | DuplicateEvent | RemoveEvent | FindingsEvent | TableEvent | BeliefsEvent
+)
+
+// Event is a change to a Node or Network reported to listeners.
+type Event struct {
+	Kind EventKind
+	Net  *Network
+	// Node is the Node that changed, or nil for events on the Network as a whole.
+	Node *Node
+}
+
+// listener is a callback registered for kinds of events on a Node or Network.
+type listener struct {
+	id       int
+	kinds    EventKind
+	callback func(Event)
+}
+
+// listenerRegistry holds Go listeners by the Netica object they listen on, as C callbacks cannot hold Go pointers.
+type listenerRegistry struct {
+	sync.Mutex
+
+	next       int
+	objects    map[unsafe.Pointer][]*listener
+	nets       map[unsafe.Pointer]*Network
+	registered map[unsafe.Pointer]bool
+}
+
+var listeners = listenerRegistry{
+	objects:    make(map[unsafe.Pointer][]*listener),
+	nets:       make(map[unsafe.Pointer]*Network),
+	registered: make(map[unsafe.Pointer]bool),
+}
+
+// add registers callback for kinds of events on object in net, returning a function removing it
+// and whether the Netica listener for object still has to be added.
+func (reg *listenerRegistry) add(object unsafe.Pointer, net *Network, kinds EventKind, callback func(Event)) (func(), bool) {
+	reg.Lock()
+	defer reg.Unlock()
+	reg.next++
+	id := reg.next
+	reg.objects[object] = append(reg.objects[object], &listener{id, kinds, callback})
+	reg.nets[object] = net
+	first := !reg.registered[object]
+	reg.registered[object] = true
+	remove := func() {
+		reg.Lock()
+		defer reg.Unlock()
+		for index, other := range reg.objects[object] {
+			if other.id == id {
+				reg.objects[object] = append(reg.objects[object][:index:index], reg.objects[object][index+1:]...)
+				break
+			}
+		}
+	}
+	return remove, first
+}
+
+// removeObject removes all listeners on object.
+func (reg *listenerRegistry) removeObject(object unsafe.Pointer) {
+	reg.Lock()
+	defer reg.Unlock()
+	delete(reg.objects, object)
+	delete(reg.nets, object)
+	delete(reg.registered, object)
+}
+
+// dispatch calls each listener on object registered for the kind of event.
+func (reg *listenerRegistry) dispatch(object unsafe.Pointer, event Event) {
+	reg.Lock()
+	matched := make([]*listener, 0, len(reg.objects[object]))
+	for _, listener := range reg.objects[object] {
+		if listener.kinds&event.Kind != 0 {
+			matched = append(matched, listener)
+		}
+	}
+	reg.Unlock()
+	// Call listeners without holding the registry so they may add or remove listeners
+	for _, listener := range matched {
+		listener.callback(event)
+	}
+}
+
+// network returns the Network registered with listeners on object.
+func (reg *listenerRegistry) network(object unsafe.Pointer) *Network {
+	reg.Lock()
+	defer reg.Unlock()
+	return reg.nets[object]
+}
+
+//export goNodeEvent
+func goNodeEvent(cNode *C.node_bn, what C.int) C.int {
+	object := unsafe.Pointer(cNode)
+	net := listeners.network(object)
+	if net == nil {
+		return 0
+	}
+	event := Event{EventKind(what), net, &Node{cNode, net}}
+	listeners.dispatch(object, event)
+	listeners.dispatch(unsafe.Pointer(net.c), event)
+	if event.Kind == RemoveEvent {
+		listeners.removeObject(object)
+	}
+	return 0
+}
+
+//export goNetEvent
+func goNetEvent(cNet *C.net_bn, what C.int) C.int {
+	object := unsafe.Pointer(cNet)
+	net := listeners.network(object)
+	if net == nil {
+		return 0
+	}
+	listeners.dispatch(object, Event{EventKind(what), net, nil})
+	if EventKind(what) == RemoveEvent {
+		listeners.removeObject(object)
+	}
+	return 0
+}
+
+// AddListener calls callback for each event of kinds on the Node, returning a function removing the listener.
+// Callbacks run synchronously on the goroutine making the change, which holds any lock on the Network,
+// so they must not lock the Network or block for long.
+func (node *Node) AddListener(kinds EventKind, callback func(Event)) func() {
+	object := unsafe.Pointer(node.c)
+	remove, first := listeners.add(object, node.Net, kinds, callback)
+	if first {
+		C.addNodeListener(node.c, C.int(CreateEvent|DuplicateEvent|RemoveEvent))
+	}
+	return remove
+}
+
+// AddListener calls callback for each event of kinds on the Network or any of its Nodes,
+// returning a function removing the listener. Callbacks run as for Node.AddListener.
+// Nodes deleted with DeleteNode raise RemoveEvent whether or not listeners are added on them.
+func (net *Network) AddListener(kinds EventKind, callback func(Event)) func() {
+	object := unsafe.Pointer(net.c)
+	remove, first := listeners.add(object, net, kinds, callback)
+	if first {
+		C.addNetListener(net.c, C.int(CreateEvent|DuplicateEvent|RemoveEvent))
+	}
+	return remove
+}
+
+// Events returns a channel with room for buffer events receiving each event of kinds on the Node,
+// and a function removing the listener. Events are queued and sent in order from a separate goroutine,
+// never while the Network is locked, so the channel may be received from by a goroutine locking the Network.
+func (node *Node) Events(kinds EventKind, buffer int) (<-chan Event, func()) {
+	events := make(chan Event, buffer)
+	queue := newEventQueue(events)
+	remove := node.AddListener(kinds, queue.push)
+	return events, func() {
+		remove()
+		queue.stop()
+	}
+}
+
+// Events returns a channel receiving each event of kinds on the Network or any of its Nodes,
+// and a function removing the listener. Events are sent as for Node.Events.
+func (net *Network) Events(kinds EventKind, buffer int) (<-chan Event, func()) {
+	events := make(chan Event, buffer)
+	queue := newEventQueue(events)
+	remove := net.AddListener(kinds, queue.push)
+	return events, func() {
+		remove()
+		queue.stop()
+	}
+}
+
+// eventQueue holds events raised by changes until they are sent on a channel by its own goroutine.
+type eventQueue struct {
+	sync.Mutex
+
+	pending []Event
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// newEventQueue returns an eventQueue sending events on events until stopped.
+func newEventQueue(events chan<- Event) *eventQueue {
+	queue := &eventQueue{wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go queue.send(events)
+	return queue
+}
+
+// push queues event without blocking.
+func (queue *eventQueue) push(event Event) {
+	queue.Lock()
+	queue.pending = append(queue.pending, event)
+	queue.Unlock()
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stop ends sending, dropping events not yet received.
+func (queue *eventQueue) stop() {
+	close(queue.done)
+}
+
+// send sends queued events on events in order until stopped.
+func (queue *eventQueue) send(events chan<- Event) {
+	for {
+		select {
+		case <-queue.wake:
+		case <-queue.done:
+			return
+		}
+		// Take all pending events and send them without holding the queue
+		queue.Lock()
+		pending := queue.pending
+		queue.pending = nil
+		queue.Unlock()
+		for _, event := range pending {
+			select {
+			case events <- event:
+			case <-queue.done:
+				return
+			}
+		}
+	}
+}
+
+// emit raises event on the Node, which is also reported to listeners on its Network.
+func (node *Node) emit(kind EventKind) {
+	node.Net.changed(kind)
+	event := Event{kind, node.Net, node}
+	listeners.dispatch(unsafe.Pointer(node.c), event)
+	listeners.dispatch(unsafe.Pointer(node.Net.c), event)
+}
+
+// emit raises event on the Network as a whole.
+func (net *Network) emit(kind EventKind) {
+	net.changed(kind)
+	listeners.dispatch(unsafe.Pointer(net.c), Event{kind, net, nil})
+}
+
+// changed marks beliefs of the Network for update if findings or tables changed,
+// and marks findings as entered if findings changed.
+func (net *Network) changed(kind EventKind) {
+	state, ok := net.env.netstates[net.c]
+	if !ok {
+		return
+	}
+	if kind&(FindingsEvent|TableEvent) != 0 {
+		state.updated = false
+	}
+	if kind&FindingsEvent != 0 {
+		state.findings = true
+	}
+}
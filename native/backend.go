@@ -0,0 +1,246 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package native is a pure-Go exact inference backend for Bayesnets read from .dne files,
+// using variable elimination so that Bayesnets can be served without cgo or the Netica library.
+//
+// Only nature nodes with complete probability tables are supported, continuous nodes must be discretised.
+package native
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/slee21/gonetica"
+	"github.com/slee21/gonetica/dne"
+)
+
+// Backend is the gonetica.Backend performing inference in Go.
+type Backend struct{}
+
+// Network is a Bayesnet loaded by the native Backend.
+type Network struct {
+	sync.RWMutex
+
+	name    string
+	title   string
+	comment string
+	nodes   []*Node
+	byName  map[string]*Node
+
+	// beliefs caches the beliefs of each node given the current findings, nil once findings change.
+	beliefs map[*Node][]float64
+}
+
+// Node is a node of a Network loaded by the native Backend.
+type Node struct {
+	net      *Network
+	index    int
+	name     string
+	title    string
+	comment  string
+	kind     gonetica.NodeKind
+	discrete bool
+	states   []string
+	levels   []float64
+	parents  []*Node
+	children []*Node
+	// cpt is the conditional probability table as a factor over the parents then the node.
+	cpt *factor
+	// finding is the likelihood of each state entered as finding, nil if none.
+	finding []float64
+	// visual is the display information of the node, nil if none.
+	visual *dne.Visual
+}
+
+// NewBackend returns a new native Backend.
+func NewBackend() *Backend {
+	return &Backend{}
+}
+
+// Load parses buf holding the contents of a .dne file with name into a new Network.
+// Load options are not supported and must be zero.
+func (backend *Backend) Load(name string, buf []byte, options gonetica.LoadOptions) (gonetica.BackendNetwork, error) {
+	// Check file format and options are supported
+	if ext := filepath.Ext(name); ext != ".dne" {
+		return nil, fmt.Errorf("In function native.Load: unsupported file format %s, save as .dne", ext)
+	}
+	if options.TimeSlices != 0 || len(options.Levels) != 0 || options.Bins != 0 || options.EquationSamples != 0 {
+		return nil, fmt.Errorf("In function native.Load: load options not supported for %s", name)
+	}
+	// Parse file and check for errors
+	model, err := dne.Read(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return NewNetwork(model)
+}
+
+// NewNetwork returns a new Network for inference on model.
+func NewNetwork(model *dne.Net) (*Network, error) {
+	net := &Network{name: model.Name, title: model.Title, comment: model.Comment, byName: make(map[string]*Node)}
+	// Create nodes and check they are supported
+	for index, nodeModel := range model.Nodes {
+		node := &Node{
+			net:      net,
+			index:    index,
+			name:     nodeModel.Name,
+			title:    nodeModel.Title,
+			comment:  nodeModel.Comment,
+			kind:     nodeModel.Kind,
+			discrete: nodeModel.Discrete,
+			states:   nodeModel.States,
+			levels:   nodeModel.Levels,
+			visual:   nodeModel.Visual,
+		}
+		if node.kind != gonetica.NatureNode && node.kind != gonetica.ConstantNode {
+			return nil, fmt.Errorf("In function native.NewNetwork: %s node %s not supported", node.kind, node.name)
+		}
+		if nodeModel.NumStates() == 0 {
+			return nil, fmt.Errorf("In function native.NewNetwork: node %s must be discretised", node.name)
+		}
+		if !node.discrete && len(node.states) == 0 {
+			node.states = make([]string, nodeModel.NumStates())
+		}
+		// Check states and levels agree so tables and intervals are indexed within bounds
+		if len(node.states) != nodeModel.NumStates() || (!node.discrete && len(node.levels) != len(node.states)+1) {
+			return nil, fmt.Errorf("In function native.NewNetwork: node %s has %d states but %d levels", node.name, len(node.states), len(node.levels))
+		}
+		net.nodes = append(net.nodes, node)
+		net.byName[node.name] = node
+	}
+	// Link nodes and build probability tables as factors
+	for index, nodeModel := range model.Nodes {
+		node := net.nodes[index]
+		var vars, card []int
+		for _, name := range nodeModel.Parents {
+			parent, ok := net.byName[name]
+			if !ok {
+				return nil, fmt.Errorf("In function native.NewNetwork: parent %s of node %s not defined", name, node.name)
+			}
+			node.parents = append(node.parents, parent)
+			parent.children = append(parent.children, node)
+			vars = append(vars, parent.index)
+			card = append(card, len(parent.states))
+		}
+		vars = append(vars, node.index)
+		card = append(card, len(node.states))
+		table, err := cptTable(nodeModel)
+		if err != nil {
+			return nil, err
+		}
+		size := 1
+		for _, states := range card {
+			size *= states
+		}
+		if len(table) != size {
+			return nil, fmt.Errorf("In function native.NewNetwork: node %s has %d probabilities but %d parent and node state combinations", node.name, len(table), size)
+		}
+		node.cpt = &factor{vars, card, table}
+	}
+	return net, nil
+}
+
+// cptTable returns the probability table of nodeModel flattened in order, checking it is complete.
+func cptTable(nodeModel *dne.Node) ([]float64, error) {
+	var table []float64
+	if nodeModel.Probs == nil {
+		return nil, fmt.Errorf("In function native.NewNetwork: node %s has no probability table", nodeModel.Name)
+	}
+	for _, row := range nodeModel.Probs {
+		for _, prob := range row {
+			if prob != prob || prob < 0 {
+				return nil, fmt.Errorf("In function native.NewNetwork: node %s has undefined or negative probabilities", nodeModel.Name)
+			}
+		}
+		table = append(table, row...)
+	}
+	return table, nil
+}
+
+// Name returns the name of the Network.
+func (net *Network) Name() string {
+	return net.name
+}
+
+// Title returns the title of the Network.
+func (net *Network) Title() string {
+	return net.title
+}
+
+// Comment returns the comment of the Network.
+func (net *Network) Comment() string {
+	return net.comment
+}
+
+// NodeList returns a Slice of Nodes sorted by name lexicographically ascending.
+func (net *Network) NodeList() ([]gonetica.BackendNode, error) {
+	var nodes []gonetica.BackendNode
+	for _, node := range net.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name() < nodes[j].Name()
+	})
+	return nodes, nil
+}
+
+// NodeNamed returns Node in net with name.
+func (net *Network) NodeNamed(name string) (gonetica.BackendNode, error) {
+	node, ok := net.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("In function Network.NodeNamed: node %s not defined for network %s", name, net.name)
+	}
+	return node, nil
+}
+
+// EnterCase enters a set of findings into the network, see Node.EnterFinding for the evidence syntax.
+// Unknown nodes are skipped and all findings are retracted on error.
+func (net *Network) EnterCase(caseMap map[string]string) error {
+	for name, evidence := range caseMap {
+		node, ok := net.byName[name]
+		if !ok {
+			continue
+		}
+		// Enter findings for each node in case, retract all findings on error
+		if err := node.EnterFinding(evidence); err != nil {
+			net.ClearCases()
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearCases retracts all findings in the network.
+func (net *Network) ClearCases() error {
+	for _, node := range net.nodes {
+		node.finding = nil
+	}
+	net.beliefs = nil
+	return nil
+}
+
+// FindingsProbability returns the joint probability of all findings currently entered in the network.
+func (net *Network) FindingsProbability() (float64, error) {
+	_, prob := net.eliminate(nil)
+	return prob, nil
+}
+
+// CloseNetwork closes the Network, which holds no resources outside Go.
+func (net *Network) CloseNetwork() error {
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package native
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/slee21/gonetica"
+)
+
+// asiaNet is a cut down Asia Bayesnet with a discretised continuous node.
+const asiaNet = `// ~->[DNET-1]->~
+bnet ChestClinic {
+node Smoking {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (smoker, nonsmoker);
+	parents = ();
+	probs = (0.5, 0.5);
+	};
+node Cancer {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (present, absent);
+	parents = (Smoking);
+	probs = ((0.1, 0.9), (0.01, 0.99));
+	};
+node XRay {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (abnormal, normal);
+	parents = (Cancer);
+	probs = ((0.98, 0.02), (0.05, 0.95));
+	};
+node Age {
+	kind = NATURE;
+	discrete = FALSE;
+	levels = (0, 40, 60, 100);
+	parents = (Smoking);
+	probs = ((0.2, 0.5, 0.3), (0.5, 0.3, 0.2));
+	};
+};
+`
+
+func TestBeliefList(t *testing.T) {
+	net, err := NewBackend().Load("asia.dne", []byte(asiaNet), gonetica.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		findings map[string]string
+		node     string
+		beliefs  []float64
+	}{
+		{nil, "Cancer", []float64{0.055, 0.945}},
+		{map[string]string{"Smoking": "smoker"}, "Cancer", []float64{0.1, 0.9}},
+		// P(Cancer | XRay abnormal) = 0.055*0.98 / (0.055*0.98 + 0.945*0.05)
+		{map[string]string{"XRay": "abnormal"}, "Cancer", []float64{0.5329, 0.4671}},
+		{map[string]string{"Age": "70"}, "Smoking", []float64{0.6, 0.4}},
+		{map[string]string{"Smoking": "L(1,3)"}, "Age", []float64{0.425, 0.35, 0.225}},
+		// Closed intervals include the states they touch, P(Smoking | Age in [40, 60)) = 0.5*0.5 / (0.5*0.5 + 0.5*0.3)
+		{map[string]string{"Age": "[40,40]"}, "Smoking", []float64{0.625, 0.375}},
+		{map[string]string{"Age": "[20,40]"}, "Age", []float64{0.35 / 0.75, 0.4 / 0.75, 0}},
+	}
+	for _, test := range tests {
+		if err := net.EnterCase(test.findings); err != nil {
+			t.Fatal(err)
+		}
+		node, err := net.NodeNamed(test.node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		beliefs, err := node.BeliefList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for index := range test.beliefs {
+			if math.Abs(beliefs[index]-test.beliefs[index]) > 1e-4 {
+				t.Errorf("%v: beliefs of %s %v, want %v", test.findings, test.node, beliefs, test.beliefs)
+				break
+			}
+		}
+		// Check modifying beliefs returned leaves those cached intact
+		beliefs[0] = -1
+		if cached, _ := node.BeliefList(); cached[0] == -1 {
+			t.Errorf("%v: beliefs of %s share cache with caller", test.findings, test.node)
+		}
+		net.ClearCases()
+	}
+}
+
+func TestCPT(t *testing.T) {
+	net, err := NewBackend().Load("asia.dne", []byte(asiaNet), gonetica.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := net.NodeNamed("Age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := node.CPT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float64{{0.2, 0.5, 0.3}, {0.5, 0.3, 0.2}}
+	if len(table) != len(want) || len(table[1]) != 3 || table[1][0] != want[1][0] || table[0][2] != want[0][2] {
+		t.Errorf("CPT of Age: got %v, want %v", table, want)
+	}
+}
+
+func TestLoadRejectsMismatchedNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		node string
+	}{
+		{"states and levels disagree", `node Age {
+	discrete = FALSE;
+	states = (young, middle, old);
+	levels = (0, 1, 2);
+	parents = ();
+	probs = (0.5, 0.5);
+	};`},
+		{"state count disagrees with interval levels", `node Age {
+	discrete = FALSE;
+	states = (young, old);
+	levels = (0, 40, 60, 100);
+	parents = ();
+	probs = (0.2, 0.5, 0.3);
+	};`},
+	}
+	for _, test := range tests {
+		buf := "bnet Bad {\n" + test.node + "\n};\n"
+		if _, err := NewBackend().Load("bad.dne", []byte(buf), gonetica.LoadOptions{}); err == nil {
+			t.Errorf("%s: loaded without error", test.name)
+		} else if !strings.Contains(err.Error(), "Age") {
+			t.Errorf("%s: error %q does not name the node", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package native
+
+// factor is a table of non-negative values over combinations of states of vars,
+// ordered with the state of the last var varying fastest.
+type factor struct {
+	vars  []int
+	card  []int
+	table []float64
+}
+
+// stride returns the step in the table of f for a change in state of v, or 0 if f is not over v.
+func (f *factor) stride(v int) int {
+	step := 1
+	for index := len(f.vars) - 1; index >= 0; index-- {
+		if f.vars[index] == v {
+			return step
+		}
+		step *= f.card[index]
+	}
+	return 0
+}
+
+// multiply returns the product of factors a and b over the union of their vars.
+func multiply(a, b *factor) *factor {
+	result := &factor{vars: append([]int(nil), a.vars...), card: append([]int(nil), a.card...)}
+	for index, v := range b.vars {
+		if !containsVar(a.vars, v) {
+			result.vars = append(result.vars, v)
+			result.card = append(result.card, b.card[index])
+		}
+	}
+	size := 1
+	for _, card := range result.card {
+		size *= card
+	}
+	result.table = make([]float64, size)
+	// Iterate over states of result vars tracking offsets into a and b
+	strideA := make([]int, len(result.vars))
+	strideB := make([]int, len(result.vars))
+	for index, v := range result.vars {
+		strideA[index] = a.stride(v)
+		strideB[index] = b.stride(v)
+	}
+	states := make([]int, len(result.vars))
+	offsetA, offsetB := 0, 0
+	for index := range result.table {
+		result.table[index] = a.table[offsetA] * b.table[offsetB]
+		// Advance states with last var fastest
+		for v := len(states) - 1; v >= 0; v-- {
+			states[v]++
+			offsetA += strideA[v]
+			offsetB += strideB[v]
+			if states[v] < result.card[v] {
+				break
+			}
+			offsetA -= strideA[v] * states[v]
+			offsetB -= strideB[v] * states[v]
+			states[v] = 0
+		}
+	}
+	return result
+}
+
+// sumOut returns f with v summed out.
+func sumOut(f *factor, v int) *factor {
+	result := &factor{}
+	var pos int
+	for index, other := range f.vars {
+		if other == v {
+			pos = index
+			continue
+		}
+		result.vars = append(result.vars, other)
+		result.card = append(result.card, f.card[index])
+	}
+	step := f.stride(v)
+	card := f.card[pos]
+	result.table = make([]float64, len(f.table)/card)
+	// Sum entries differing only in state of v
+	for index := range result.table {
+		outer, inner := index/step, index%step
+		base := outer*step*card + inner
+		for state := 0; state < card; state++ {
+			result.table[index] += f.table[base+state*step]
+		}
+	}
+	return result
+}
+
+// containsVar returns whether vars contains v.
+func containsVar(vars []int, v int) bool {
+	for _, other := range vars {
+		if other == v {
+			return true
+		}
+	}
+	return false
+}
+
+// eliminate sums out every node except target from the joint distribution of the Network with findings,
+// returning the unnormalised beliefs of target and the probability of the findings. Target may be nil.
+// Only ancestors of target and nodes with findings are relevant, others sum out to 1.
+func (net *Network) eliminate(target *Node) ([]float64, float64) {
+	// Collect relevant nodes by walking up from target and nodes with findings
+	relevant := make(map[*Node]bool)
+	var visit func(*Node)
+	visit = func(node *Node) {
+		if relevant[node] {
+			return
+		}
+		relevant[node] = true
+		for _, parent := range node.parents {
+			visit(parent)
+		}
+	}
+	if target != nil {
+		visit(target)
+	}
+	for _, node := range net.nodes {
+		if node.finding != nil {
+			visit(node)
+		}
+	}
+	// Build factors from tables and findings of relevant nodes
+	var factors []*factor
+	var pending []*Node
+	for _, node := range net.nodes {
+		if !relevant[node] {
+			continue
+		}
+		factors = append(factors, node.cpt)
+		if node.finding != nil {
+			factors = append(factors, &factor{[]int{node.index}, []int{len(node.states)}, node.finding})
+		}
+		if node != target {
+			pending = append(pending, node)
+		}
+	}
+	// Eliminate nodes one at a time, choosing the node giving the smallest product
+	for len(pending) > 0 {
+		best, bestSize := 0, -1
+		for index, node := range pending {
+			if size := productSize(factors, node.index); bestSize < 0 || size < bestSize {
+				best, bestSize = index, size
+			}
+		}
+		v := pending[best].index
+		pending = append(pending[:best], pending[best+1:]...)
+		// Multiply factors over v and sum it out
+		var product *factor
+		var rest []*factor
+		for _, f := range factors {
+			if containsVar(f.vars, v) {
+				if product == nil {
+					product = f
+				} else {
+					product = multiply(product, f)
+				}
+			} else {
+				rest = append(rest, f)
+			}
+		}
+		factors = append(rest, sumOut(product, v))
+	}
+	// Multiply remaining factors over target or no vars
+	result := &factor{table: []float64{1}}
+	for _, f := range factors {
+		result = multiply(result, f)
+	}
+	if target == nil {
+		return nil, result.table[0]
+	}
+	var prob float64
+	for _, value := range result.table {
+		prob += value
+	}
+	return result.table, prob
+}
+
+// productSize returns the size of the product of factors over v.
+func productSize(factors []*factor, v int) int {
+	var vars []int
+	size := 1
+	for _, f := range factors {
+		if !containsVar(f.vars, v) {
+			continue
+		}
+		for index, other := range f.vars {
+			if !containsVar(vars, other) {
+				vars = append(vars, other)
+				size *= f.card[index]
+			}
+		}
+	}
+	return size
+}
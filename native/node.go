@@ -0,0 +1,367 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package native
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/slee21/gonetica"
+)
+
+// Name returns the name of the Node.
+func (node *Node) Name() string {
+	return node.name
+}
+
+// Title returns the title of the Node.
+func (node *Node) Title() string {
+	return node.title
+}
+
+// Comment returns the comment of the Node.
+func (node *Node) Comment() string {
+	return node.comment
+}
+
+// Kind returns the kind of the Node.
+func (node *Node) Kind() gonetica.NodeKind {
+	return node.kind
+}
+
+// IsDiscreteType returns bool whether node is discrete type.
+func (node *Node) IsDiscreteType() bool {
+	return node.discrete
+}
+
+// StateNameList returns a Slice of state names in order.
+func (node *Node) StateNameList() ([]string, error) {
+	return node.states, nil
+}
+
+// LevelList returns a Slice of level floats in order.
+func (node *Node) LevelList() ([]float64, error) {
+	return node.levels, nil
+}
+
+// ParentList returns a Slice of parent Nodes in the order of the conditional probability table.
+func (node *Node) ParentList() ([]gonetica.BackendNode, error) {
+	return backendNodes(node.parents), nil
+}
+
+// ChildList returns a Slice of child Nodes.
+func (node *Node) ChildList() ([]gonetica.BackendNode, error) {
+	return backendNodes(node.children), nil
+}
+
+// backendNodes returns nodes as BackendNodes in order.
+func backendNodes(nodes []*Node) []gonetica.BackendNode {
+	var wrapped []gonetica.BackendNode
+	for _, node := range nodes {
+		wrapped = append(wrapped, node)
+	}
+	return wrapped
+}
+
+// ParseState returns index of state given as #index or state name.
+func (node *Node) ParseState(state string) (int, error) {
+	// Try to parse state as state index
+	if strings.HasPrefix(state, "#") {
+		index, err := strconv.Atoi(strings.TrimPrefix(state, "#"))
+		if err == nil && index >= 0 && index < len(node.states) {
+			return index, nil
+		}
+	}
+	// Try to lookup state by name
+	for index, name := range node.states {
+		if name != "" && name == state {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("In function Node.ParseState: state %s not defined for node %s", state, node.name)
+}
+
+// EnterFinding enters an evidence string which may be one of:
+//
+//	stateName or #index   discrete state
+//	1.5                   real value, the state with that level or the interval containing it
+//	L(w1,w2,...)          likelihood vector with one weight per state
+//	[low,high]            interval of real values, for a discretised continuous node
+//	~N(mean,stdDev)       Gaussian distributed real value, for a discretised continuous node
+//	!stateA,#1,...        negative finding ruling out each listed state
+func (node *Node) EnterFinding(evidence string) error {
+	likelihood, err := node.parseFinding(evidence)
+	if err != nil {
+		return err
+	}
+	// Check finding rules out not every state
+	var total float64
+	for _, weight := range likelihood {
+		if weight < 0 {
+			return fmt.Errorf("In function Node.EnterFinding: negative likelihood %s for node %s", evidence, node.name)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("In function Node.EnterFinding: finding %s rules out every state of node %s", evidence, node.name)
+	}
+	node.finding = likelihood
+	node.net.beliefs = nil
+	return nil
+}
+
+// parseFinding returns the likelihood of each state given evidence.
+func (node *Node) parseFinding(evidence string) ([]float64, error) {
+	length := len(node.states)
+	switch {
+	// Try to parse evidence as negative finding
+	case strings.HasPrefix(evidence, "!"):
+		likelihood := uniform(length)
+		for _, name := range strings.Split(strings.TrimPrefix(evidence, "!"), ",") {
+			index, err := node.ParseState(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			likelihood[index] = 0
+		}
+		return likelihood, nil
+	// Try to parse evidence as interval
+	case strings.HasPrefix(evidence, "[") && strings.HasSuffix(evidence, "]"):
+		bounds, err := parseFloatList(strings.TrimSuffix(strings.TrimPrefix(evidence, "["), "]"))
+		if err != nil || len(bounds) != 2 || node.discrete {
+			return nil, fmt.Errorf("In function Node.EnterFinding: invalid interval %s for node %s", evidence, node.name)
+		}
+		// Rule in each state whose interval, including its low level, meets the closed interval
+		likelihood := make([]float64, length)
+		for index := range likelihood {
+			low, high := node.interval(index)
+			if low <= bounds[1] && bounds[0] < high {
+				likelihood[index] = 1
+			}
+		}
+		return likelihood, nil
+	// Try to parse evidence as Gaussian
+	case strings.HasPrefix(evidence, "~N(") && strings.HasSuffix(evidence, ")"):
+		params, err := parseFloatList(strings.TrimSuffix(strings.TrimPrefix(evidence, "~N("), ")"))
+		if err != nil || len(params) != 2 || params[1] <= 0 || node.discrete {
+			return nil, fmt.Errorf("In function Node.EnterFinding: invalid Gaussian %s for node %s", evidence, node.name)
+		}
+		// Weight each interval by its probability under the Gaussian
+		cdf := func(x float64) float64 {
+			return (1 + math.Erf((x-params[0])/(params[1]*math.Sqrt2))) / 2
+		}
+		likelihood := make([]float64, length)
+		for index := range likelihood {
+			low, high := node.interval(index)
+			likelihood[index] = cdf(high) - cdf(low)
+		}
+		return likelihood, nil
+	// Try to parse evidence as likelihood vector
+	case strings.HasPrefix(evidence, "L(") && strings.HasSuffix(evidence, ")"):
+		likelihood, err := parseFloatList(strings.TrimSuffix(strings.TrimPrefix(evidence, "L("), ")"))
+		if err != nil || len(likelihood) != length {
+			return nil, fmt.Errorf("In function Node.EnterFinding: invalid likelihood %s for node %s", evidence, node.name)
+		}
+		return likelihood, nil
+	}
+	// Try to parse evidence as real value
+	if value, err := strconv.ParseFloat(evidence, 64); err == nil {
+		index, err := node.valueState(value)
+		if err != nil {
+			return nil, err
+		}
+		likelihood := make([]float64, length)
+		likelihood[index] = 1
+		return likelihood, nil
+	}
+	// Try to parse evidence as state index or name
+	index, err := node.ParseState(evidence)
+	if err != nil {
+		return nil, err
+	}
+	likelihood := make([]float64, length)
+	likelihood[index] = 1
+	return likelihood, nil
+}
+
+// valueState returns the state of the node holding real value, the interval containing it
+// for a continuous node or the state with it as level for a discrete node.
+func (node *Node) valueState(value float64) (int, error) {
+	for index := range node.states {
+		if node.discrete {
+			if index < len(node.levels) && node.levels[index] == value {
+				return index, nil
+			}
+			continue
+		}
+		if low, high := node.interval(index); low <= value && value < high {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("In function Node.EnterFinding: value %g not in any state of node %s", value, node.name)
+}
+
+// interval returns the bounds of state index of a discretised continuous node in ascending order.
+func (node *Node) interval(index int) (float64, float64) {
+	low, high := node.levels[index], node.levels[index+1]
+	if low > high {
+		low, high = high, low
+	}
+	return low, high
+}
+
+// HasFinding returns whether a finding is entered for the node.
+func (node *Node) HasFinding() bool {
+	return node.finding != nil
+}
+
+// Position returns the center of the node in the visual display of its network, ok is false if it has none.
+func (node *Node) Position() (float64, float64, bool) {
+	if node.visual == nil {
+		return 0, 0, false
+	}
+	return node.visual.X, node.visual.Y, true
+}
+
+// ClearFindings retracts all findings for the node.
+func (node *Node) ClearFindings() error {
+	node.finding = nil
+	node.net.beliefs = nil
+	return nil
+}
+
+// BeliefList returns Slice of belief floats in order of states.
+// The Slice is a copy which callers may modify.
+func (node *Node) BeliefList() ([]float64, error) {
+	net := node.net
+	if beliefs, ok := net.beliefs[node]; ok {
+		return append([]float64(nil), beliefs...), nil
+	}
+	// Eliminate all other nodes and normalise, check for conflicting findings
+	beliefs, prob := net.eliminate(node)
+	if prob == 0 {
+		return nil, fmt.Errorf("In function Node.BeliefList: findings in network %s are inconsistent", net.name)
+	}
+	for index := range beliefs {
+		beliefs[index] /= prob
+	}
+	if net.beliefs == nil {
+		net.beliefs = make(map[*Node][]float64)
+	}
+	net.beliefs[node] = beliefs
+	return append([]float64(nil), beliefs...), nil
+}
+
+// CPT returns the conditional probability table of the node, with one row of state probabilities
+// per combination of parent states, ordered with the state of the last parent varying fastest.
+func (node *Node) CPT() ([][]float64, error) {
+	var table [][]float64
+	length := len(node.states)
+	for start := 0; start < len(node.cpt.table); start += length {
+		table = append(table, append([]float64(nil), node.cpt.table[start:start+length]...))
+	}
+	return table, nil
+}
+
+// State returns most likely state of node
+func (node *Node) State() (int, error) {
+	// Get list of node beliefs
+	beliefList, err := node.BeliefList()
+	if err != nil {
+		return 0, err
+	}
+	// Return first state with max belief
+	maxIndex := 0
+	for index, belief := range beliefList {
+		if belief > beliefList[maxIndex] {
+			maxIndex = index
+		}
+	}
+	return maxIndex, nil
+}
+
+// Value returns expected value and standard deviation of node, over the levels of a discrete node
+// or the interval midpoints of a discretised continuous node.
+func (node *Node) Value() (float64, float64, error) {
+	// Get real value of each state
+	var values []float64
+	switch {
+	case node.discrete && len(node.levels) == len(node.states):
+		values = node.levels
+	case !node.discrete:
+		for index := range node.states {
+			low, high := node.interval(index)
+			values = append(values, (low+high)/2)
+		}
+	default:
+		return 0, 0, fmt.Errorf("%s: %s", "In function Node.Value", "undefined expected value")
+	}
+	// Calculate mean and standard deviation over beliefs
+	beliefs, err := node.BeliefList()
+	if err != nil {
+		return 0, 0, err
+	}
+	var mean, square float64
+	for index, value := range values {
+		mean += beliefs[index] * value
+		square += beliefs[index] * value * value
+	}
+	if math.IsInf(mean, 0) || math.IsNaN(mean) {
+		return 0, 0, fmt.Errorf("%s: %s", "In function Node.Value", "undefined expected value")
+	}
+	return mean, math.Sqrt(math.Max(square-mean*mean, 0)), nil
+}
+
+// Infer attempts to infer the value or state of the node.
+func (node *Node) Infer() (string, error) {
+	// Try to return a real value estimate
+	value, _, err := node.Value()
+	if err == nil {
+		return strconv.FormatFloat(value, 'E', -1, 64), nil
+	}
+	// Try to return a discrete state estimate
+	index, err := node.State()
+	if err != nil {
+		return "", err
+	}
+	if node.states[index] != "" {
+		return node.states[index], nil
+	}
+	return fmt.Sprintf("#%d", index), nil
+}
+
+// uniform returns a likelihood of 1 for each of length states.
+func uniform(length int) []float64 {
+	likelihood := make([]float64, length)
+	for index := range likelihood {
+		likelihood[index] = 1
+	}
+	return likelihood
+}
+
+// parseFloatList parses a comma separated list of floats.
+func parseFloatList(list string) ([]float64, error) {
+	var floats []float64
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		floats = append(floats, value)
+	}
+	return floats, nil
+}
@@ -12,6 +12,9 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
 package gonetica
 
 /*
@@ -33,11 +36,13 @@ import "C"
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
-	"sync"
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -46,21 +51,45 @@ type Network struct {
 	c *C.net_bn
 
 	env *Environment
+	// loading is set while the Network is prepared for inference, before it is registered in its Environment.
+	loading bool
 }
 
 // NewNetwork parses file at path into a new Network and index with key.
-func NewNetwork(environment *Environment, path string) (*Network, error) {
-	var net = new(Network)
-	var err error
-	net.env = environment
+// Options, if given, configure how the Network is prepared for inference.
+func NewNetwork(environment *Environment, path string, options ...LoadOptions) (*Network, error) {
 	// Open file for reading and check for errors
 	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
+	return NewNetworkFromReader(environment, filepath.Base(path), file, options...)
+}
+
+// NewNetworkFromReader parses the contents of r as a file with name into a new Network.
+// The extension of name determines whether the contents are parsed as .dne or .neta.
+func NewNetworkFromReader(environment *Environment, name string, r io.Reader, options ...LoadOptions) (*Network, error) {
+	// Read contents and check for errors
+	buf, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
+	return NewNetworkFromBytes(environment, name, buf, options...)
+}
+
+// NewNetworkFromBytes parses buf holding the contents of a file with name into a new Network.
+// The extension of name determines whether buf is parsed as .dne or .neta.
+func NewNetworkFromBytes(environment *Environment, name string, buf []byte, options ...LoadOptions) (*Network, error) {
+	var net = new(Network)
+	var opts LoadOptions
+	var err error
+	net.env = environment
+	net.loading = true
+	if len(options) > 0 {
+		opts = options[0]
+	}
 	// Allocate name string and check for errors
-	name := filepath.Base(path)
 	cName := (*C.char)(C.CString(name))
 	defer C.free(unsafe.Pointer(cName))
 	if err = net.Errors(); err != nil {
@@ -72,11 +101,6 @@ func NewNetwork(environment *Environment, path string) (*Network, error) {
 	if err = net.Errors(); err != nil {
 		return nil, err
 	}
-	// Read file and check for errors
-	buf, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, err
-	}
 	// Stream file into Netica and check for errors
 	cBuf := (*C.char)(C.CBytes(buf)) // C.CBytes causes spurious report prior to Go1.8: https://github.com/golang/go/issues/17563
 	defer C.free(unsafe.Pointer(cBuf))
@@ -89,23 +113,174 @@ func NewNetwork(environment *Environment, path string) (*Network, error) {
 	if err = net.Errors(); err != nil {
 		return nil, err
 	}
+	// Expand dynamic network into time slices and check for errors
+	if opts.TimeSlices > 0 {
+		cExpanded := C.ExpandNet_bn(net.c, 0, C.double(opts.TimeSlices-1), 0, nil)
+		C.DeleteNet_bn(net.c)
+		if err = net.Errors(); err != nil {
+			if cExpanded != nil {
+				C.DeleteNet_bn(cExpanded)
+			}
+			return nil, err
+		}
+		net.c = cExpanded
+	}
+	// Discretise continuous nodes and convert equations into tables and check for errors
+	if err = net.discretise(opts); err != nil {
+		C.DeleteNet_bn(net.c)
+		return nil, err
+	}
 	// Compile network in Netica and check for errors
 	C.CompileNet_bn(net.c)
 	if err = net.Errors(); err != nil {
+		C.DeleteNet_bn(net.c)
 		return nil, err
 	}
 	// Retract any findings in network and check for errors
 	C.RetractNetFindings_bn(net.c)
 	if err = net.Errors(); err != nil {
+		C.DeleteNet_bn(net.c)
+		return nil, err
+	}
+	// Turn off automatic updating for network and check for errors
+	C.SetNetAutoUpdate_bn(net.c, C.int(0))
+	if err = net.Errors(); err != nil {
+		C.DeleteNet_bn(net.c)
+		return nil, err
+	}
+	// Register in synchronization map, beliefs are up to date with the tables loaded
+	net.env.netstates[net.c] = &netState{updated: true}
+	net.loading = false
+	return net, nil
+}
+
+// discretise sets levels of continuous nodes and converts equations into tables as configured by opts.
+func (net *Network) discretise(opts LoadOptions) error {
+	if len(opts.Levels) == 0 && opts.Bins <= 0 && opts.EquationSamples <= 0 {
+		return nil
+	}
+	nodes, err := net.NodeList()
+	if err != nil {
+		return err
+	}
+	// Discretise continuous nodes first so equations of children are converted over parent intervals
+	for _, node := range nodes {
+		if !node.IsContinuousType() {
+			continue
+		}
+		levels, ok := opts.Levels[node.Name()]
+		if !ok && opts.Bins > 0 && C.GetNodeLevels_bn(node.c) == nil {
+			if levels, err = binLevels(opts.Bins, opts.Low, opts.High); err != nil {
+				return err
+			}
+		}
+		if levels != nil {
+			if err := node.SetLevels(levels); err != nil {
+				return err
+			}
+		}
+	}
+	// Convert equations into tables
+	if opts.EquationSamples > 0 {
+		for _, node := range nodes {
+			if node.Equation() == "" {
+				continue
+			}
+			if err := node.EquationToTable(opts.EquationSamples); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// binLevels returns the boundaries of bins equal intervals from low to high, which must be above low.
+func binLevels(bins int, low, high float64) ([]float64, error) {
+	if high <= low {
+		return nil, fmt.Errorf("In function binLevels: high %g must be above low %g to discretise into %d bins", high, low, bins)
+	}
+	levels := make([]float64, bins+1)
+	for index := range levels {
+		levels[index] = low + (high-low)*float64(index)/float64(bins)
+	}
+	return levels, nil
+}
+
+// WriteTo writes the Network to w in .dne format.
+func (net *Network) WriteTo(w io.Writer) (int64, error) {
+	// Serialise network and check for errors
+	buf, err := net.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// Save writes the Network to file at path, in .neta format if path has extension .neta and .dne otherwise.
+func (net *Network) Save(path string) error {
+	// Serialise network and check for errors
+	buf, err := net.serialise(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf, 0644)
+}
+
+// Bytes returns the Network serialised in .dne format.
+func (net *Network) Bytes() ([]byte, error) {
+	return net.serialise(net.Name() + ".dne")
+}
+
+// serialise returns the Network written as a file with name.
+func (net *Network) serialise(name string) ([]byte, error) {
+	// Allocate name string
+	cName := (*C.char)(C.CString(name))
+	defer C.free(unsafe.Pointer(cName))
+	// Allocate memory stream and check for errors
+	cStrm := C.NewMemoryStream_ns(cName, net.env.c, nil)
+	defer C.DeleteStream_ns(cStrm)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	// Write network into stream and check for errors
+	C.WriteNet_bn(net.c, cStrm)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	// Copy stream contents into Go memory
+	var cLen C.long
+	cBuf := C.GetStreamContents_ns(cStrm, &cLen)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	return C.GoBytes(unsafe.Pointer(cBuf), C.int(cLen)), nil
+}
+
+// NewEmptyNetwork returns a new Network with name and no nodes.
+func NewEmptyNetwork(environment *Environment, name string) (*Network, error) {
+	var net = new(Network)
+	var err error
+	net.env = environment
+	// Allocate name string
+	cName := (*C.char)(C.CString(name))
+	defer C.free(unsafe.Pointer(cName))
+	// Create Netica network and check for errors
+	net.c = C.NewNet_bn(cName, net.env.c)
+	if err = net.Errors(); err != nil {
+		if net.c != nil {
+			C.DeleteNet_bn(net.c)
+		}
 		return nil, err
 	}
 	// Turn off automatic updating for network and check for errors
 	C.SetNetAutoUpdate_bn(net.c, C.int(0))
 	if err = net.Errors(); err != nil {
+		C.DeleteNet_bn(net.c)
 		return nil, err
 	}
-	// Register in synchronization map
-	net.env.netlocks[net.c] = new(sync.RWMutex)
+	// Register in synchronization map, marked for compilation
+	net.env.netstates[net.c] = &netState{stale: true, updated: true}
 	return net, nil
 }
 
@@ -113,11 +288,141 @@ func NewNetwork(environment *Environment, path string) (*Network, error) {
 func (net *Network) CloseNetwork() error {
 	// Delete network from Environment
 	C.DeleteNet_bn(net.c)
-	// Delete from synchronization map
-	delete(net.env.netlocks, net.c)
+	// Delete from synchronization map and listener registry
+	delete(net.env.netstates, net.c)
+	listeners.removeObject(unsafe.Pointer(net.c))
 	return net.Errors()
 }
 
+// Compile compiles the Network for inference, which is required after its structure or tables change.
+func (net *Network) Compile() error {
+	// Compile network in Netica and check for errors
+	C.CompileNet_bn(net.c)
+	if err := net.Errors(); err != nil {
+		return err
+	}
+	if state, ok := net.env.netstates[net.c]; ok {
+		state.stale = false
+	}
+	return nil
+}
+
+// compileStale compiles the Network if marked for recompilation since it was last compiled,
+// before beliefs are queried. Raises BeliefsEvent if findings or tables changed since the last query.
+// Networks still being loaded are not registered and are compiled on every query, closed Networks return an error.
+func (net *Network) compileStale() error {
+	state, ok := net.env.netstates[net.c]
+	if !ok {
+		if net.loading {
+			return net.Compile()
+		}
+		return fmt.Errorf("In function compileStale: network closed")
+	}
+	if state.stale {
+		if err := net.Compile(); err != nil {
+			return err
+		}
+	}
+	if !state.updated {
+		state.updated = true
+		net.emit(BeliefsEvent)
+	}
+	return nil
+}
+
+// markStale marks the Network for recompilation.
+// Networks still being loaded are not registered and are compiled once loaded.
+func (net *Network) markStale() {
+	if state, ok := net.env.netstates[net.c]; ok {
+		state.stale = true
+	}
+}
+
+// AddNode adds a new Node of kind with name to the Network.
+// A discrete node is added with states as state names and levels, if any, as the real value of each state.
+// A continuous node is added if states is empty, discretised into len(levels)-1 intervals if levels are given.
+func (net *Network) AddNode(name string, kind NodeKind, states []string, levels []float64) (*Node, error) {
+	// Allocate name string
+	cName := (*C.char)(C.CString(name))
+	defer C.free(unsafe.Pointer(cName))
+	// Create Netica node and check for errors
+	cNode := C.NewNode_bn(cName, C.int(len(states)), net.c)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	node := &Node{cNode, net}
+	net.markStale()
+	// Set kind of node and check for errors
+	C.SetNodeKind_bn(cNode, C.nodekind_bn(kind))
+	if err := net.Errors(); err != nil {
+		net.DeleteNode(node)
+		return nil, err
+	}
+	// Set name of each state and check for errors
+	for index, state := range states {
+		cState := (*C.char)(C.CString(state))
+		C.SetNodeStateName_bn(cNode, C.state_bn(index), cState)
+		C.free(unsafe.Pointer(cState))
+		if err := net.Errors(); err != nil {
+			net.DeleteNode(node)
+			return nil, err
+		}
+	}
+	// Set levels of node and check for errors
+	if len(levels) > 0 {
+		if err := node.SetLevels(levels); err != nil {
+			net.DeleteNode(node)
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// DeleteNode removes node and its links from the Network.
+// Raises RemoveEvent on the node before it is deleted, and TableEvent on each child, whose table loses the node as parent.
+func (net *Network) DeleteNode(node *Node) error {
+	// Get children and findings before deleting and check for errors
+	children, err := node.ChildList()
+	if err != nil {
+		return err
+	}
+	hadFinding := node.HasFinding()
+	// Report removal to listeners on the node and Network, then remove node listeners so Netica does not report it again
+	node.emit(RemoveEvent)
+	listeners.removeObject(unsafe.Pointer(node.c))
+	C.DeleteNode_bn(node.c)
+	if err := net.Errors(); err != nil {
+		return err
+	}
+	net.markStale()
+	for _, child := range children {
+		child.emit(TableEvent)
+	}
+	if hadFinding {
+		net.emit(FindingsEvent)
+	}
+	return nil
+}
+
+// newNodeList returns a new Netica node list of nodes in the Network, which must be deleted after use.
+func (net *Network) newNodeList(nodes []*Node) (*C.nodelist_bn, error) {
+	cNodes := C.NewNodeList2_bn(0, net.c)
+	for _, node := range nodes {
+		// Check node is in network
+		if node.Net.c != net.c {
+			C.DeleteNodeList_bn(cNodes)
+			return nil, fmt.Errorf("In function Network.newNodeList: node %s not in network %s", node.Name(), net.Name())
+		}
+		C.AddNodeToList_bn(node.c, cNodes, C.LAST_ENTRY)
+	}
+	// Check for errors
+	if err := net.Errors(); err != nil {
+		C.DeleteNodeList_bn(cNodes)
+		return nil, err
+	}
+	return cNodes, nil
+}
+
 // Errors returns all Netica errors of severity level error since it was last called.
 func (net *Network) Errors() error {
 	return net.env.Errors()
@@ -138,7 +443,7 @@ func (net *Network) Comment() string {
 	return C.GoString(C.GetNetComment_bn(net.c))
 }
 
-// NodeNamed returns Node in net with name.
+// NodeNamed returns Node in net with name, or name@time for a node at a time slice of an expanded dynamic Network.
 func (net *Network) NodeNamed(name string) (*Node, error) {
 	var node *Node
 	// Allocate string name
@@ -147,12 +452,35 @@ func (net *Network) NodeNamed(name string) (*Node, error) {
 	// Search underlying c network for node with name, error if not found
 	cNode := C.GetNodeNamed_bn(cName, net.c)
 	if cNode == nil {
+		// Search for node at time slice if name is name@time
+		if at := strings.LastIndex(name, "@"); at > 0 {
+			if slice, err := strconv.ParseFloat(name[at+1:], 64); err == nil {
+				return net.NodeAtTime(name[:at], slice)
+			}
+		}
 		return nil, fmt.Errorf("In function Network.NodeNamed: node %s not defined for network %s", name, net.Name())
 	}
 	node = &Node{cNode, net}
 	return node, nil
 }
 
+// NodeAtTime returns the Node in an expanded dynamic Network for the node with name at time slice.
+func (net *Network) NodeAtTime(name string, slice float64) (*Node, error) {
+	// Allocate string name
+	cName := (*C.char)(C.CString(name))
+	defer C.free(unsafe.Pointer(cName))
+	cTime := C.double(slice)
+	// Search underlying c network for node at time, error if not found
+	cNode := C.GetNodeAtTime_bn(net.c, cName, &cTime)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	if cNode == nil {
+		return nil, fmt.Errorf("In function Network.NodeAtTime: node %s at time %g not defined for network %s", name, slice, net.Name())
+	}
+	return &Node{cNode, net}, nil
+}
+
 // NodeMap returns a Map of Nodes in the Network indexed by name.
 func (net *Network) NodeMap() (map[string]*Node, error) {
 	var nodes = make(map[string]*Node)
@@ -193,15 +521,21 @@ func (net *Network) NodeList() ([]*Node, error) {
 	return nodes, nil
 }
 
-// EnterCase enters a set of findings into the network.
+// EnterCase enters a set of findings into the network, see Node.EnterFinding for the evidence syntax.
 func (net *Network) EnterCase(caseMap map[string]string) error {
 	// Get network nodes mapped by name and check for errors
 	nodeMap, err := net.NodeMap()
 	if err != nil {
 		return err
 	}
-	for name, node := range nodeMap {
-		if evidence, ok := caseMap[name]; ok {
+	for name, evidence := range caseMap {
+		node, ok := nodeMap[name]
+		// Lookup node at time slice if name is name@time, skip unknown nodes
+		if !ok && strings.Contains(name, "@") {
+			node, err = net.NodeNamed(name)
+			ok = err == nil
+		}
+		if ok {
 			// Enter findings for each node in case
 			err := node.EnterFinding(evidence)
 			// Check for errors, retract all findings on error
@@ -214,29 +548,161 @@ func (net *Network) EnterCase(caseMap map[string]string) error {
 	return nil
 }
 
-// ClearCases retracts all findings in the network.
+// FindingsProbability returns the joint probability of all findings currently entered in the network.
+func (net *Network) FindingsProbability() (float64, error) {
+	// Compile network if marked for recompilation and check for errors
+	if err := net.compileStale(); err != nil {
+		return 0, err
+	}
+	prob := float64(C.FindingsProbability_bn(net.c))
+	// Check for errors
+	if err := net.Errors(); err != nil {
+		return 0, err
+	}
+	return prob, nil
+}
+
+// MostProbableConfig returns the state of each of nodes in the nth most probable configuration
+// of the Network given findings entered, counting from 0. Nodes must list every node in the Network,
+// all nodes in the order of NodeList are used if nodes is nil.
+func (net *Network) MostProbableConfig(nodes []*Node, nth int) ([]int, error) {
+	var config []int
+	var err error
+	// Default to all nodes and check for errors
+	if nodes == nil {
+		nodes, err = net.NodeList()
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Compile network if marked for recompilation and check for errors
+	if err = net.compileStale(); err != nil {
+		return nil, err
+	}
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(nodes)
+	if err != nil {
+		return nil, err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Find configuration and check for errors
+	cConfig := toCStates(make([]int, len(nodes)))
+	C.MostProbableConfig_bn(cNodes, &cConfig[0], C.int(nth))
+	if err = net.Errors(); err != nil {
+		return nil, err
+	}
+	for index := range nodes {
+		config = append(config, int(cConfig[index]))
+	}
+	return config, nil
+}
+
+// JointProbability returns the joint probability that each of nodes is in the state at the same index
+// of states given findings entered.
+func (net *Network) JointProbability(nodes []*Node, states []int) (float64, error) {
+	// Check a state is given for each node
+	if len(nodes) != len(states) {
+		return 0, fmt.Errorf("In function Network.JointProbability: %d states given for %d nodes", len(states), len(nodes))
+	}
+	// Compile network if marked for recompilation and check for errors
+	if err := net.compileStale(); err != nil {
+		return 0, err
+	}
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(nodes)
+	if err != nil {
+		return 0, err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Calculate probability and check for errors
+	cStates := toCStates(states)
+	prob := float64(C.JointProbability_bn(cNodes, &cStates[0]))
+	if err = net.Errors(); err != nil {
+		return 0, err
+	}
+	return prob, nil
+}
+
+// OptimizeDecisions finds the optimal policy of every decision node in the Network, maximising expected utility.
+// The tables of decision nodes are replaced by their policies, read them first with Node.CPT to restore them.
+func (net *Network) OptimizeDecisions() error {
+	// Compile network if marked for recompilation and check for errors
+	if err := net.compileStale(); err != nil {
+		return err
+	}
+	C.OptimizeDecisions_bn(C.GetNetNodes2_bn(net.c, nil))
+	if err := net.Errors(); err != nil {
+		return err
+	}
+	// Tables of decision nodes are replaced by their optimal policies
+	nodes, err := net.NodeList()
+	if err != nil {
+		return err
+	}
+	net.markStale()
+	for _, node := range nodes {
+		if node.Kind() == DecisionNode {
+			node.emit(TableEvent)
+		}
+	}
+	return nil
+}
+
+// ExpectedUtility returns the expected utility of the Network given findings entered and optimal decisions.
+func (net *Network) ExpectedUtility() (float64, error) {
+	// Compile network if marked for recompilation and check for errors
+	if err := net.compileStale(); err != nil {
+		return 0, err
+	}
+	utility := float64(C.GetNetExpectedUtility_bn(net.c))
+	// Check for errors
+	if err := net.Errors(); err != nil {
+		return 0, err
+	}
+	return utility, nil
+}
+
+// ClearCases retracts all findings in the network, raising FindingsEvent if any were entered.
 func (net *Network) ClearCases() error {
 	// Retract any findings in network and check for errors
 	C.RetractNetFindings_bn(net.c)
-	return net.Errors()
+	if err := net.Errors(); err != nil {
+		return err
+	}
+	// Raise event only if findings were entered since all were last retracted
+	if state, ok := net.env.netstates[net.c]; ok && state.findings {
+		net.emit(FindingsEvent)
+		state.findings = false
+	}
+	return nil
+}
+
+// WriteDOT writes the nodes and links of the network to w as a GraphViz DOT graph, see GraphOptions.
+func (net *Network) WriteDOT(w io.Writer, options GraphOptions) error {
+	return WriteDOT(w, &neticaNetwork{net}, options)
+}
+
+// WriteSVG writes the nodes and links of the network to w as an SVG image, see GraphOptions.
+func (net *Network) WriteSVG(w io.Writer, options GraphOptions) error {
+	return WriteSVG(w, &neticaNetwork{net}, options)
 }
 
 // Lock acquires lock for writing to underlying C network.
 func (net *Network) Lock() {
-	net.env.netlocks[net.c].Lock()
+	net.env.netstates[net.c].Lock()
 }
 
 // RLock acquires lock for reading from underlying C network.
 func (net *Network) RLock() {
-	net.env.netlocks[net.c].RLock()
+	net.env.netstates[net.c].RLock()
 }
 
 // RUnlock releases lock for reading from underlying C network.
 func (net *Network) RUnlock() {
-	net.env.netlocks[net.c].RUnlock()
+	net.env.netstates[net.c].RUnlock()
 }
 
 // Unlock releases lock for writing to underlying C network.
 func (net *Network) Unlock() {
-	net.env.netlocks[net.c].Unlock()
+	net.env.netstates[net.c].Unlock()
 }
@@ -12,6 +12,9 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
 package gonetica
 
 /*
@@ -32,6 +35,7 @@ package gonetica
 import "C"
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"unsafe"
@@ -44,6 +48,20 @@ type Node struct {
 	Net *Network
 }
 
+// EveryState matches every state of a parent when setting probabilities.
+const EveryState = C.EVERY_STATE
+
+// SensitivityKind is the measure of how much a finding at one Node would change beliefs of another.
+type SensitivityKind int
+
+// Sensitivity measures supported by Netica.
+const (
+	// MutualInfoSensitivity is the expected reduction in entropy, for discrete target nodes.
+	MutualInfoSensitivity SensitivityKind = C.ENTROPY_SENSV
+	// VarianceSensitivity is the expected reduction in variance of the real value, for target nodes with levels.
+	VarianceSensitivity SensitivityKind = C.VARIANCE_OF_REAL_SENSV
+)
+
 // Errors returns all Netica errors of severity level error since it was last called.
 func (node *Node) Errors() error {
 	return node.Net.Errors()
@@ -64,6 +82,11 @@ func (node *Node) Comment() string {
 	return C.GoString(C.GetNodeComment_bn(node.c))
 }
 
+// Kind returns the kind of the Node.
+func (node *Node) Kind() NodeKind {
+	return NodeKind(C.GetNodeKind_bn(node.c))
+}
+
 // IsDiscreteType returns bool whether node is discrete type.
 func (node *Node) IsDiscreteType() bool {
 	return C.GetNodeType_bn(node.c) == C.DISCRETE_TYPE
@@ -74,6 +97,57 @@ func (node *Node) IsContinuousType() bool {
 	return C.GetNodeType_bn(node.c) == C.CONTINUOUS_TYPE
 }
 
+// ParentList returns a Slice of parent Nodes in the order of the conditional probability table.
+func (node *Node) ParentList() ([]*Node, error) {
+	return node.nodeList(C.GetNodeParents_bn(node.c))
+}
+
+// ChildList returns a Slice of child Nodes.
+func (node *Node) ChildList() ([]*Node, error) {
+	return node.nodeList(C.GetNodeChildren_bn(node.c))
+}
+
+// nodeList returns a Slice of Nodes in the order of cNodes.
+func (node *Node) nodeList(cNodes *C.nodelist_bn) ([]*Node, error) {
+	var nodes []*Node
+	// Iterate over Netica nodes and save as Node in nodes
+	for index := C.int(0); index < C.LengthNodeList_bn(cNodes); index++ {
+		nodes = append(nodes, &Node{C.NthNode_bn(cNodes, index), node.Net})
+	}
+	// Check for errors
+	if err := node.Errors(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// AddParent adds a link from parent to the Node, raising TableEvent as its table gains a dimension.
+func (node *Node) AddParent(parent *Node) error {
+	C.AddLink_bn(parent.c, node.c)
+	if err := node.Errors(); err != nil {
+		return err
+	}
+	node.Net.markStale()
+	node.emit(TableEvent)
+	return nil
+}
+
+// RemoveParent removes the link from parent to the Node, raising TableEvent as its table loses a dimension.
+func (node *Node) RemoveParent(parent *Node) error {
+	// Find index of link from parent and check for errors
+	index := C.IndexOfNodeInList_bn(parent.c, C.GetNodeParents_bn(node.c), 0)
+	if index < 0 {
+		return fmt.Errorf("In function Node.RemoveParent: node %s is not a parent of node %s", parent.Name(), node.Name())
+	}
+	C.DeleteLink_bn(index, node.c)
+	if err := node.Errors(); err != nil {
+		return err
+	}
+	node.Net.markStale()
+	node.emit(TableEvent)
+	return nil
+}
+
 // StateNamed returns index of state with name if exists error otherwise.
 func (node *Node) StateNamed(name string) (int, error) {
 	var index int
@@ -132,61 +206,388 @@ func (node *Node) LevelList() ([]float64, error) {
 	return levels, nil
 }
 
-// SetState enters a state finding for a discrete type node.
-func (node *Node) SetState(state int) error {
-	C.EnterFinding_bn(node.c, C.state_bn(state))
-	// Check for errors, clear node findings on error
+// SetLevels sets the levels of the Node, removing them if levels is empty.
+// A discrete node takes one level per state as the real value of each state,
+// a continuous node is discretised into len(levels)-1 states with levels as interval boundaries.
+func (node *Node) SetLevels(levels []float64) error {
+	var cLevels []C.level_bn
+	var cFirst *C.level_bn
+	// Get number of states based on node type
+	numStates := len(levels)
+	if numStates == 0 && node.IsDiscreteType() {
+		numStates = int(C.GetNodeNumberStates_bn(node.c))
+	} else if numStates > 0 && node.IsContinuousType() {
+		numStates--
+	}
+	// Copy levels into level vector
+	for _, level := range levels {
+		cLevels = append(cLevels, C.level_bn(level))
+	}
+	if len(cLevels) > 0 {
+		cFirst = &cLevels[0]
+	}
+	C.SetNodeLevels_bn(node.c, C.int(numStates), cFirst)
 	if err := node.Errors(); err != nil {
-		node.ClearFindings()
 		return err
 	}
+	node.Net.markStale()
+	node.emit(TableEvent)
+	return nil
+}
+
+// Equation returns the equation of the Node, or an empty string if it has none.
+func (node *Node) Equation() string {
+	return C.GoString(C.GetNodeEquation_bn(node.c))
+}
+
+// SetEquation sets the equation of the Node, removing it if equation is empty.
+// The table is not changed until EquationToTable is called.
+func (node *Node) SetEquation(equation string) error {
+	var cEquation *C.char
+	if equation != "" {
+		cEquation = C.CString(equation)
+		defer C.free(unsafe.Pointer(cEquation))
+	}
+	C.SetNodeEquation_bn(node.c, cEquation)
+	return node.Errors()
+}
+
+// EquationToTable replaces the conditional probability table of the Node with one built from its equation,
+// drawing samples per parent state combination to account for parent intervals and uncertainty.
+func (node *Node) EquationToTable(samples int) error {
+	C.EquationToTable_bn(node.c, C.int(samples), C.TRUE, C.FALSE)
+	if err := node.Errors(); err != nil {
+		return err
+	}
+	node.Net.markStale()
+	node.emit(TableEvent)
+	return nil
+}
+
+// HasCPT returns whether the Node has a conditional probability table and whether it is complete.
+func (node *Node) HasCPT() (bool, bool) {
+	var cComplete C.bool_ns
+	has := C.HasNodeTable_bn(node.c, &cComplete)
+	return has != 0, cComplete != 0
+}
+
+// CPT returns the conditional probability table of the Node, or nil if it has none.
+// The table has one row of state probabilities per combination of parent states,
+// ordered with the state of the last parent varying fastest. Undefined entries are NaN.
+func (node *Node) CPT() ([][]float64, error) {
+	var table [][]float64
+	// Return nil table for node without table
+	if has, _ := node.HasCPT(); !has {
+		return nil, nil
+	}
+	// Get number of states of each parent and check for errors
+	radices, err := node.parentRadices()
+	if err != nil {
+		return nil, err
+	}
+	length := C.GetNodeNumberStates_bn(node.c)
+	// Iterate over parent state combinations saving probability floats in table
+	parentStates := make([]int, len(radices))
+	for more := true; more; more = nextStates(parentStates, radices) {
+		cParentStates := toCStates(parentStates)
+		cProbs := C.GetNodeProbs_bn(node.c, &cParentStates[0])
+		if err := node.Errors(); err != nil {
+			return nil, err
+		}
+		var probs []float64
+		for index := C.int(0); index < length; index++ {
+			if cProbs == nil {
+				probs = append(probs, math.NaN())
+			} else {
+				probs = append(probs, float64(C.NthProb_bn(cProbs, C.state_bn(index))))
+			}
+		}
+		table = append(table, probs)
+	}
+	return table, nil
+}
+
+// SetCPT replaces the conditional probability table of the Node with table, in the order returned by CPT.
+func (node *Node) SetCPT(table [][]float64) error {
+	// Get number of states of each parent and check for errors
+	radices, err := node.parentRadices()
+	if err != nil {
+		return err
+	}
+	// Check table has one row per parent state combination
+	rows := 1
+	for _, radix := range radices {
+		rows *= radix
+	}
+	if len(table) != rows {
+		return fmt.Errorf("In function Node.SetCPT: %d rows given but node %s has %d parent state combinations", len(table), node.Name(), rows)
+	}
+	// Iterate over parent state combinations setting each row and check for errors
+	parentStates := make([]int, len(radices))
+	for _, probs := range table {
+		if err := node.SetProbs(parentStates, probs); err != nil {
+			return err
+		}
+		nextStates(parentStates, radices)
+	}
 	return nil
 }
 
+// SetProbs sets the probabilities of each state of the Node given the state of each parent.
+// EveryState may be given as a parent state to set all rows matching the other parent states.
+func (node *Node) SetProbs(parentStates []int, probs []float64) error {
+	length := int(C.GetNodeNumberStates_bn(node.c))
+	// Check probabilities are given for each state
+	if len(probs) != length {
+		return fmt.Errorf("In function Node.SetProbs: %d probabilities given but node %s has %d states", len(probs), node.Name(), length)
+	}
+	// Copy parent states and probabilities into C vectors
+	cParentStates := toCStates(parentStates)
+	cProbs := make([]C.prob_bn, length)
+	for index, prob := range probs {
+		cProbs[index] = C.prob_bn(prob)
+	}
+	C.SetNodeProbs_bn(node.c, &cParentStates[0], &cProbs[0])
+	node.Net.markStale()
+	node.emit(TableEvent)
+	return node.Errors()
+}
+
+// DeleteCPT removes the conditional probability table of the Node.
+func (node *Node) DeleteCPT() error {
+	C.DeleteNodeTables_bn(node.c)
+	node.Net.markStale()
+	node.emit(TableEvent)
+	return node.Errors()
+}
+
+// parentRadices returns the number of states of each parent of the Node.
+func (node *Node) parentRadices() ([]int, error) {
+	var radices []int
+	cParents := C.GetNodeParents_bn(node.c)
+	for index := C.int(0); index < C.LengthNodeList_bn(cParents); index++ {
+		cParent := C.NthNode_bn(cParents, index)
+		radix := int(C.GetNodeNumberStates_bn(cParent))
+		if radix <= 0 {
+			return nil, fmt.Errorf("In function Node.parentRadices: parent %s of node %s has no states", C.GoString(C.GetNodeName_bn(cParent)), node.Name())
+		}
+		radices = append(radices, radix)
+	}
+	// Check for errors
+	if err := node.Errors(); err != nil {
+		return nil, err
+	}
+	return radices, nil
+}
+
+// nextStates advances states to the next combination with the last state varying fastest.
+// Returns false after the last combination, when states wraps around to all zeros.
+func nextStates(states []int, radices []int) bool {
+	for index := len(radices) - 1; index >= 0; index-- {
+		states[index]++
+		if states[index] < radices[index] {
+			return true
+		}
+		states[index] = 0
+	}
+	return false
+}
+
+// toCStates copies states into a C state vector, padded so it is never empty.
+func toCStates(states []int) []C.state_bn {
+	cStates := make([]C.state_bn, len(states)+1)
+	for index, state := range states {
+		cStates[index] = C.state_bn(state)
+	}
+	return cStates
+}
+
+// SetState enters a state finding for a discrete type node.
+func (node *Node) SetState(state int) error {
+	C.EnterFinding_bn(node.c, C.state_bn(state))
+	return node.findingEntered()
+}
+
 // SetValue enters a real value finding for a continuous type node.
 func (node *Node) SetValue(value float64) error {
 	C.EnterNodeValue_bn(node.c, C.double(value))
-	// Check for errors, clear node findings on error
-	if err := node.Errors(); err != nil {
-		node.ClearFindings()
-		return err
+	return node.findingEntered()
+}
+
+// EnterLikelihood enters a likelihood (virtual) finding with one weight per state of the node.
+func (node *Node) EnterLikelihood(likelihood []float64) error {
+	length := int(C.GetNodeNumberStates_bn(node.c))
+	// Check node is discretised and likelihood has one weight per state
+	if length == 0 {
+		return fmt.Errorf("In function Node.EnterLikelihood: node %s has no states", node.Name())
+	}
+	if len(likelihood) != length {
+		return fmt.Errorf("In function Node.EnterLikelihood: %d weights given but node %s has %d states", len(likelihood), node.Name(), length)
 	}
+	// Copy weights into likelihood vector
+	cLikelihood := make([]C.prob_bn, length)
+	for index, weight := range likelihood {
+		cLikelihood[index] = C.prob_bn(weight)
+	}
+	C.EnterNodeLikelihood_bn(node.c, &cLikelihood[0])
+	return node.findingEntered()
+}
+
+// EnterInterval enters a finding that the value of a continuous node lies in the closed interval [low, high].
+func (node *Node) EnterInterval(low, high float64) error {
+	C.EnterIntervalFinding_bn(node.c, C.double(low), C.double(high))
+	return node.findingEntered()
+}
+
+// EnterGaussian enters a finding that the value of a continuous node is normally distributed.
+func (node *Node) EnterGaussian(mean, stdDev float64) error {
+	C.EnterGaussianFinding_bn(node.c, C.double(mean), C.double(stdDev))
+	return node.findingEntered()
+}
+
+// EnterNot enters a negative finding that the node is in none of states.
+func (node *Node) EnterNot(states ...int) error {
+	// Rule out each state in turn and check for errors
+	for _, state := range states {
+		C.EnterFindingNot_bn(node.c, C.state_bn(state))
+		// Check for errors, clear node findings on error
+		if err := node.Errors(); err != nil {
+			node.ClearFindings()
+			return err
+		}
+	}
+	node.emit(FindingsEvent)
 	return nil
 }
 
-// EnterFinding enters an evidence string which may be a discrete state or real value.
+// EnterAction enters the decision made at a decision node as state.
+func (node *Node) EnterAction(state int) error {
+	C.EnterAction_bn(node.c, C.state_bn(state))
+	return node.findingEntered()
+}
+
+// EnterActionRandomized enters a randomized decision at a decision node with the probability of choosing each state.
+func (node *Node) EnterActionRandomized(probs []float64) error {
+	length := int(C.GetNodeNumberStates_bn(node.c))
+	// Check probabilities are given for each state
+	if length == 0 || len(probs) != length {
+		return fmt.Errorf("In function Node.EnterActionRandomized: %d probabilities given but node %s has %d states", len(probs), node.Name(), length)
+	}
+	// Copy probabilities into probability vector
+	cProbs := make([]C.prob_bn, length)
+	for index, prob := range probs {
+		cProbs[index] = C.prob_bn(prob)
+	}
+	C.EnterActionRandomized_bn(node.c, &cProbs[0])
+	return node.findingEntered()
+}
+
+// EnterFinding enters an evidence string which may be one of:
+//
+//	stateName or #index   discrete state
+//	1.5                   real value
+//	L(w1,w2,...)          likelihood vector with one weight per state
+//	[low,high]            interval of real values
+//	~N(mean,stdDev)       Gaussian distributed real value
+//	!stateA,#1,...        negative finding ruling out each listed state
 func (node *Node) EnterFinding(evidence string) error {
+	// Try to enter evidence as negative finding
+	if strings.HasPrefix(evidence, "!") {
+		var states []int
+		for _, name := range strings.Split(strings.TrimPrefix(evidence, "!"), ",") {
+			index, err := node.ParseState(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			states = append(states, index)
+		}
+		return node.EnterNot(states...)
+	}
+	// Try to enter evidence as interval
+	if strings.HasPrefix(evidence, "[") && strings.HasSuffix(evidence, "]") {
+		bounds, err := parseFloatList(strings.TrimSuffix(strings.TrimPrefix(evidence, "["), "]"))
+		if err != nil || len(bounds) != 2 {
+			return fmt.Errorf("In function Node.EnterFinding: invalid interval %s for node %s", evidence, node.Name())
+		}
+		return node.EnterInterval(bounds[0], bounds[1])
+	}
+	// Try to enter evidence as Gaussian
+	if strings.HasPrefix(evidence, "~N(") && strings.HasSuffix(evidence, ")") {
+		params, err := parseFloatList(strings.TrimSuffix(strings.TrimPrefix(evidence, "~N("), ")"))
+		if err != nil || len(params) != 2 {
+			return fmt.Errorf("In function Node.EnterFinding: invalid Gaussian %s for node %s", evidence, node.Name())
+		}
+		return node.EnterGaussian(params[0], params[1])
+	}
+	// Try to enter evidence as likelihood vector
+	if strings.HasPrefix(evidence, "L(") && strings.HasSuffix(evidence, ")") {
+		likelihood, err := parseFloatList(strings.TrimSuffix(strings.TrimPrefix(evidence, "L("), ")"))
+		if err != nil {
+			return fmt.Errorf("In function Node.EnterFinding: invalid likelihood %s for node %s", evidence, node.Name())
+		}
+		return node.EnterLikelihood(likelihood)
+	}
 	// Try to enter evidence as real value and check for errors
 	value, err := strconv.ParseFloat(evidence, 64)
 	if err == nil {
 		return node.SetValue(value)
 	}
-	// Try to enter evidence as state index
-	if strings.HasPrefix(evidence, "#") {
-		state := strings.TrimPrefix(evidence, "#")
-		index, err := strconv.Atoi(state)
-		if err == nil {
-			return node.SetState(index)
-		}
-	}
-	// Try to enter evidence as state name and check for errors
-	index, err := node.StateNamed(evidence)
+	// Try to enter evidence as state index or name and check for errors
+	index, err := node.ParseState(evidence)
 	if err != nil {
 		return err
 	}
+	// Enter state of decision node as action
+	if node.Kind() == DecisionNode {
+		return node.EnterAction(index)
+	}
 	return node.SetState(index)
 }
 
-// ClearFindings retracts all findings for the node.
+// ParseState returns index of state given as #index or state name.
+func (node *Node) ParseState(state string) (int, error) {
+	// Try to parse state as state index
+	if strings.HasPrefix(state, "#") {
+		index, err := strconv.Atoi(strings.TrimPrefix(state, "#"))
+		if err == nil {
+			return index, nil
+		}
+	}
+	// Try to lookup state by name
+	return node.StateNamed(state)
+}
+
+// ClearFindings retracts all findings for the node, raising FindingsEvent if any were entered.
 func (node *Node) ClearFindings() error {
 	// Retract any findings in node and check for errors
+	hadFinding := node.HasFinding()
 	C.RetractNodeFindings_bn(node.c)
-	return node.Errors()
+	if err := node.Errors(); err != nil {
+		return err
+	}
+	if hadFinding {
+		node.emit(FindingsEvent)
+	}
+	return nil
+}
+
+// findingEntered checks for errors after a finding is entered, clearing node findings on error.
+func (node *Node) findingEntered() error {
+	if err := node.Errors(); err != nil {
+		node.ClearFindings()
+		return err
+	}
+	node.emit(FindingsEvent)
+	return nil
 }
 
 // BeliefList returns Slice of belief floats in order of states.
 func (node *Node) BeliefList() ([]float64, error) {
 	var beliefs []float64
+	// Compile network if marked for recompilation and check for errors
+	if err := node.Net.compileStale(); err != nil {
+		return nil, err
+	}
 	cBeliefs := C.GetNodeBeliefs_bn(node.c)
 	length := C.GetNodeNumberStates_bn(node.c)
 	// Iterate over node beliefs saving belief floats in beliefs
@@ -200,6 +601,65 @@ func (node *Node) BeliefList() ([]float64, error) {
 	return beliefs, nil
 }
 
+// Sensitivity returns how much a finding at each of varyNodes would change beliefs of the Node
+// given findings currently entered, measured as kind, in the order of varyNodes.
+func (node *Node) Sensitivity(varyNodes []*Node, kind SensitivityKind) ([]float64, error) {
+	var values []float64
+	// Compile network if marked for recompilation and check for errors
+	if err := node.Net.compileStale(); err != nil {
+		return nil, err
+	}
+	// Build Netica node list and check for errors
+	cNodes, err := node.Net.newNodeList(varyNodes)
+	if err != nil {
+		return nil, err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Create sensitivity measurer and check for errors
+	cSensv := C.NewSensvToFinding_bn(node.c, cNodes, C.int(kind))
+	if err := node.Errors(); err != nil {
+		return nil, err
+	}
+	defer C.DeleteSensvToFinding_bn(cSensv)
+	// Iterate over vary nodes saving sensitivity floats in values
+	for _, vary := range varyNodes {
+		if kind == VarianceSensitivity {
+			values = append(values, float64(C.GetVarianceOfReal_bn(cSensv, vary.c)))
+		} else {
+			values = append(values, float64(C.GetMutualInfo_bn(cSensv, vary.c)))
+		}
+	}
+	// Check for errors
+	if err := node.Errors(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// ExpectedUtilities returns Slice of expected utility floats of a decision node in order of states,
+// given findings entered and optimal decisions at later decision nodes.
+func (node *Node) ExpectedUtilities() ([]float64, error) {
+	var utilities []float64
+	// Compile network if marked for recompilation and check for errors
+	if err := node.Net.compileStale(); err != nil {
+		return nil, err
+	}
+	cUtils := C.GetNodeExpectedUtils_bn(node.c)
+	if err := node.Errors(); err != nil {
+		return nil, err
+	}
+	if cUtils == nil {
+		return nil, fmt.Errorf("In function Node.ExpectedUtilities: no expected utilities for node %s", node.Name())
+	}
+	// Iterate over node states saving utility floats in utilities, util_bn is float as is prob_bn
+	length := C.GetNodeNumberStates_bn(node.c)
+	cProbs := (*C.prob_bn)(unsafe.Pointer(cUtils))
+	for index := C.int(0); index < length; index++ {
+		utilities = append(utilities, float64(C.NthProb_bn(cProbs, C.state_bn(index))))
+	}
+	return utilities, nil
+}
+
 // State returns most likely state of node
 func (node *Node) State() (int, error) {
 	// Get list of node beliefs
@@ -223,6 +683,10 @@ func (node *Node) State() (int, error) {
 func (node *Node) Value() (float64, float64, error) {
 	var value float64
 	var stdDev float64
+	// Compile network if marked for recompilation and check for errors
+	if err := node.Net.compileStale(); err != nil {
+		return 0, 0, err
+	}
 	// Allocate memory for std dev
 	cStdDev := (*C.double)(C.malloc(C.sizeof_double))
 	defer C.free(unsafe.Pointer(cStdDev))
@@ -251,18 +715,92 @@ func (node *Node) Infer() (string, error) {
 	// Try to return a discrete state estimate
 	index, err := node.State()
 	if err == nil {
-		// Try to return state name
-		cName := C.GetNodeStateName_bn(node.c, C.state_bn(index))
-		// Check for errors
-		if err := node.Errors(); err != nil {
-			return "", err
+		return node.stateLabel(index)
+	}
+	return "", err
+}
+
+// Finding returns the finding entered for the Node as a state name, #index or real value, or "" if none.
+// Negative and likelihood findings cannot be returned.
+func (node *Node) Finding() (string, error) {
+	// Try to return real value entered for continuous node
+	if node.IsContinuousType() {
+		cValue := C.GetNodeValueEntered_bn(node.c)
+		if cValue != C.GetUndefDbl_ns() {
+			return strconv.FormatFloat(float64(cValue), 'g', -1, 64), nil
 		}
-		name := C.GoString(cName)
-		if name != "" {
-			return name, nil
+	}
+	cState := C.GetNodeFinding_bn(node.c)
+	// Check for errors
+	if err := node.Errors(); err != nil {
+		return "", err
+	}
+	switch {
+	case cState == C.NO_FINDING:
+		return "", nil
+	case cState < 0:
+		return "", fmt.Errorf("In function Node.Finding: finding of node %s is not a state or value", node.Name())
+	}
+	return node.stateLabel(int(cState))
+}
+
+// HasFinding returns whether any finding is entered for the Node, including negative and likelihood findings.
+func (node *Node) HasFinding() bool {
+	if node.IsContinuousType() && C.GetNodeValueEntered_bn(node.c) != C.GetUndefDbl_ns() {
+		return true
+	}
+	return C.GetNodeFinding_bn(node.c) != C.NO_FINDING
+}
+
+// Position returns the center of the Node in the visual display of its network, ok is false if it has none.
+func (node *Node) Position() (float64, float64, bool) {
+	// Allocate memory for coordinates
+	cX := (*C.double)(C.malloc(C.sizeof_double))
+	defer C.free(unsafe.Pointer(cX))
+	cY := (*C.double)(C.malloc(C.sizeof_double))
+	defer C.free(unsafe.Pointer(cY))
+	// Mark coordinates undefined in case Netica leaves them unset
+	*cX, *cY = C.GetUndefDbl_ns(), C.GetUndefDbl_ns()
+	C.GetNodeVisPosition_bn(node.c, nil, cX, cY)
+	// Check for errors and nodes never placed
+	if err := node.Errors(); err != nil {
+		return 0, 0, false
+	}
+	if *cX == C.GetUndefDbl_ns() || *cY == C.GetUndefDbl_ns() {
+		return 0, 0, false
+	}
+	x, y := float64(*cX), float64(*cY)
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// stateLabel returns the name of state index, or #index if unnamed.
+func (node *Node) stateLabel(index int) (string, error) {
+	// Try to return state name
+	cName := C.GetNodeStateName_bn(node.c, C.state_bn(index))
+	// Check for errors
+	if err := node.Errors(); err != nil {
+		return "", err
+	}
+	name := C.GoString(cName)
+	if name != "" {
+		return name, nil
+	}
+	// Return state index
+	return fmt.Sprintf("#%d", index), nil
+}
+
+// parseFloatList parses a comma separated list of floats.
+func parseFloatList(list string) ([]float64, error) {
+	var floats []float64
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
 		}
-		// Return state index
-		return fmt.Sprintf("#%d", index), nil
+		floats = append(floats, value)
 	}
-	return "", err
+	return floats, nil
 }
@@ -0,0 +1,139 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+/*
+#cgo darwin CFLAGS: -I"${SRCDIR}/cgo/lib/darwin"
+#cgo darwin,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/darwin/amd64"
+#cgo darwin LDFLAGS: -lm -lnetica -lpthread -lstdc++
+#cgo linux CFLAGS: -I"${SRCDIR}/cgo/lib/linux"
+#cgo linux,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/386"
+#cgo linux,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/amd64"
+#cgo linux LDFLAGS: -lm -lrt -lnetica -lpthread -lstdc++
+#cgo windows CFLAGS: -I"${SRCDIR}/cgo/lib/windows"
+#cgo windows,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/386"
+#cgo windows,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/amd64"
+#cgo windows LDFLAGS: -lm -llibNetica -lpthread -lstdc++
+#include "stdlib.h"
+#include "Netica.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+// SamplingMethod is the algorithm used to generate random cases.
+type SamplingMethod int
+
+// Sampling algorithms supported by Netica.
+const (
+	DefaultSampling  SamplingMethod = C.DEFAULT_SAMPLING
+	JoinTreeSampling SamplingMethod = C.JOIN_TREE_SAMPLING
+	ForwardSampling  SamplingMethod = C.FORWARD_SAMPLING
+)
+
+// maxSampleTries is the number of tries forward sampling may reject before giving up on a case.
+const maxSampleTries = 100000
+
+// setRandomSeed seeds the random generator of the Network so random cases are reproducible.
+func (net *Network) setRandomSeed(seed int64) error {
+	// Allocate seed string
+	cSeed := (*C.char)(C.CString(strconv.FormatInt(seed, 10)))
+	defer C.free(unsafe.Pointer(cSeed))
+	// Create random generator owned by network and check for errors
+	cRand := C.NewRandomGenerator_ns(cSeed, net.env.c, nil)
+	if err := net.Errors(); err != nil {
+		return err
+	}
+	C.SetNetRandomGen_bn(net.c, cRand, C.TRUE)
+	return net.Errors()
+}
+
+// Sample returns n random cases mapping each of nodes to a state name or real value,
+// conditioned on findings entered at other nodes. The random generator of the Network is
+// seeded with seed first, so the same seed and findings give the same cases.
+// Nodes must not have findings entered, all nodes without findings are sampled if nodes is nil.
+// Findings entered while sampling are retracted before returning, leaving findings and beliefs
+// as they were, so no events are raised.
+func (net *Network) Sample(n int, nodes []*Node, method SamplingMethod, seed int64) ([]map[string]string, error) {
+	var cases []map[string]string
+	var err error
+	// Default to all nodes without findings and check for errors
+	if nodes == nil {
+		all, err := net.NodeList()
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range all {
+			if !node.HasFinding() {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	// Check findings of sampled nodes would not be overwritten
+	for _, node := range nodes {
+		if node.HasFinding() {
+			return nil, fmt.Errorf("In function Network.Sample: node %s to sample has findings entered", node.Name())
+		}
+	}
+	// Seed random generator and check for errors
+	if err = net.setRandomSeed(seed); err != nil {
+		return nil, err
+	}
+	// Compile network if marked for recompilation and check for errors
+	if err = net.compileStale(); err != nil {
+		return nil, err
+	}
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(nodes)
+	if err != nil {
+		return nil, err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Retract sampled findings when done
+	defer func() {
+		for _, node := range nodes {
+			C.RetractNodeFindings_bn(node.c)
+		}
+	}()
+	// Generate each case as findings and read them back
+	for index := 0; index < n; index++ {
+		for _, node := range nodes {
+			C.RetractNodeFindings_bn(node.c)
+		}
+		res := C.GenerateRandomCase_bn(cNodes, C.sampling_bn(method), C.double(maxSampleTries), nil)
+		if err = net.Errors(); err != nil {
+			return nil, err
+		}
+		if res < 0 {
+			return nil, fmt.Errorf("In function Network.Sample: failed to generate case %d consistent with findings", index)
+		}
+		caseMap := make(map[string]string)
+		for _, node := range nodes {
+			caseMap[node.Name()], err = node.Finding()
+			if err != nil {
+				return nil, err
+			}
+		}
+		cases = append(cases, caseMap)
+	}
+	return cases, nil
+}
@@ -0,0 +1,102 @@
+// Copyright © 2017 Lee Sheng Long <s.lee.21@warwick.ac.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !nonetica
+// +build cgo,!nonetica
+
+package gonetica
+
+/*
+#cgo darwin CFLAGS: -I"${SRCDIR}/cgo/lib/darwin"
+#cgo darwin,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/darwin/amd64"
+#cgo darwin LDFLAGS: -lm -lnetica -lpthread -lstdc++
+#cgo linux CFLAGS: -I"${SRCDIR}/cgo/lib/linux"
+#cgo linux,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/386"
+#cgo linux,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/linux/amd64"
+#cgo linux LDFLAGS: -lm -lrt -lnetica -lpthread -lstdc++
+#cgo windows CFLAGS: -I"${SRCDIR}/cgo/lib/windows"
+#cgo windows,386 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/386"
+#cgo windows,amd64 LDFLAGS: -L"${SRCDIR}/cgo/lib/windows/amd64"
+#cgo windows LDFLAGS: -lm -llibNetica -lpthread -lstdc++
+#include "stdlib.h"
+#include "Netica.h"
+*/
+import "C"
+
+import "fmt"
+
+// TestResult is the accuracy of the beliefs of a target Node tested against a Caseset.
+type TestResult struct {
+	Node *Node
+
+	// ErrorRate is the fraction of cases where the most likely state was not the actual state.
+	ErrorRate float64
+	// LogLoss is the mean negative log belief in the actual state.
+	LogLoss float64
+	// QuadraticLoss is the mean Brier score of the beliefs.
+	QuadraticLoss float64
+	// Confusion counts cases by predicted state, then actual state.
+	Confusion [][]float64
+}
+
+// Test enters each case in cases with targets unobserved and compares the beliefs of targets to their actual states.
+func (net *Network) Test(cases *Caseset, targets []*Node) ([]*TestResult, error) {
+	var results []*TestResult
+	// Check targets are given
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("In function Network.Test: no target nodes")
+	}
+	// Compile network if marked for recompilation and check for errors
+	if err := net.compileStale(); err != nil {
+		return nil, err
+	}
+	// Build Netica node list and check for errors
+	cNodes, err := net.newNodeList(targets)
+	if err != nil {
+		return nil, err
+	}
+	defer C.DeleteNodeList_bn(cNodes)
+	// Create tester with targets unobserved and check for errors
+	cTester := C.NewNetTester_bn(cNodes, cNodes, -1)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	defer C.DeleteNetTester_bn(cTester)
+	// Test with cases and check for errors
+	C.TestWithCaseset_bn(cTester, cases.c)
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	// Iterate over targets saving test results
+	for _, node := range targets {
+		result := &TestResult{Node: node}
+		result.ErrorRate = float64(C.GetTestErrorRate_bn(cTester, node.c))
+		result.LogLoss = float64(C.GetTestLogLoss_bn(cTester, node.c))
+		result.QuadraticLoss = float64(C.GetTestQuadraticLoss_bn(cTester, node.c))
+		length := C.GetNodeNumberStates_bn(node.c)
+		for predicted := C.int(0); predicted < length; predicted++ {
+			var row []float64
+			for actual := C.int(0); actual < length; actual++ {
+				row = append(row, float64(C.GetTestConfusion_bn(cTester, node.c, C.state_bn(predicted), C.state_bn(actual))))
+			}
+			result.Confusion = append(result.Confusion, row)
+		}
+		results = append(results, result)
+	}
+	// Check for errors
+	if err := net.Errors(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}